@@ -55,6 +55,57 @@ const ZoneLabel = "v1.min.io/zone"
 // Revision is applied to all statefulsets
 const Revision = "min.io/revision"
 
+// RollbackToAnnotation 设置后将 MinIO 实例回滚到指定版本号的规格，值为 0 时回滚到上一个版本
+const RollbackToAnnotation = "v1alpha1.bob.com/rollback-to"
+
+// DefaultRevisionHistoryLimit 默认保留的 ControllerRevision 数量
+const DefaultRevisionHistoryLimit = 10
+
+// PodTemplateHashAnnotation 记录 Pod 模板渲染结果的哈希值，用于检测是否需要滚动更新
+const PodTemplateHashAnnotation = "v1alpha1.bob.com/pod-template-hash"
+
+// MinIOFinalizer 用于在删除 MinIO 实例前停止服务并处理 PVC
+const MinIOFinalizer = "minio.bob.com/finalizer"
+
+// OrphanedLabel 标记 MinIO 实例删除后保留下来的 PVC 及配置 Secret，可在重新创建同名实例时复用
+const OrphanedLabel = "v1alpha1.bob.com/orphaned"
+
+// ServerTopologyAnnotation 记录 MinIO 启动参数中服务池拓扑的哈希值，拓扑变化时所有服务池需要同时重启
+const ServerTopologyAnnotation = "v1alpha1.bob.com/server-topology"
+
+// ConfigurationHashAnnotation 记录配置 Secret 内容的哈希值，配置变化时触发滚动更新
+const ConfigurationHashAnnotation = "v1alpha1.bob.com/configuration-hash"
+
+// ConditionCredentialsReady 表示配置 Secret 中的 root 用户名和密码是否可用
+const ConditionCredentialsReady = "CredentialsReady"
+
+// ConditionAvailable 表示所有服务池的 Pod 是否都已运行
+const ConditionAvailable = "Available"
+
+// ConditionProgressing 表示是否正在部署、滚动更新、迁移或下线服务池
+const ConditionProgressing = "Progressing"
+
+// ConditionDegraded 表示最近一次调谐是否因错误而中断
+const ConditionDegraded = "Degraded"
+
+// ConditionHealthy 表示 MinIO 服务健康检查是否通过
+const ConditionHealthy = "Healthy"
+
+// RotateCredentialsAnnotation 的值变化时重新生成 root 用户名和密码，例如设置为当前时间
+const RotateCredentialsAnnotation = "v1alpha1.bob.com/rotate-credentials"
+
+// PreviousConfigurationKey 轮换 root 凭证期间在配置 Secret 中保存原有的 config.env，用于验证失败时回滚
+const PreviousConfigurationKey = "config.env.previous"
+
+// ConfigurationVolumeName 配置 Secret 在 Pod 中的卷名称
+const ConfigurationVolumeName = "configuration"
+
+// MigratedFromAnnotation 记录由直接创建 Pod 时期的 PVC 迁移而来的 PVC 的原名称
+const MigratedFromAnnotation = "v1alpha1.bob.com/migrated-from"
+
+// ReclaimPolicyAnnotation 记录 PV 在迁移前的回收策略，迁移完成后恢复
+const ReclaimPolicyAnnotation = "v1alpha1.bob.com/reclaim-policy"
+
 // MinIOPort specifies the default Tenant port number.
 const MinIOPort = 9000
 
@@ -165,9 +216,27 @@ var DefaultQueryInterval = time.Second * 5
 // DefaultQueryTimeout specifies the timeout for query for CSR Status
 var DefaultQueryTimeout = time.Minute * 20
 
+// DefaultCertExpiryWarningThresholds 证书剩余有效期低于这些阈值时产生 Warning 事件
+var DefaultCertExpiryWarningThresholds = []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}
+
 // TLSSecretSuffix is the suffix applied to Tenant name to create the TLS secret
 var TLSSecretSuffix = "-tls"
 
+// CertManagerSecretSuffix cert-manager Certificate 及其签发的证书 Secret 的名称后缀
+const CertManagerSecretSuffix = "-certmanager-tls"
+
+// CertificateHashAnnotation 记录所有证书 Secret 内容的哈希值，证书续期后触发滚动更新
+const CertificateHashAnnotation = "v1alpha1.bob.com/certificate-hash"
+
+// CertificateDNSHashAnnotation 记录证书中域名列表的哈希值，服务池变化后需要重新签发证书
+const CertificateDNSHashAnnotation = "v1alpha1.bob.com/certificate-dns-hash"
+
+// CertificateVolumeName 证书在 Pod 中的卷名称
+const CertificateVolumeName = "certs"
+
+// ConditionTLSReady 表示开启 TLS 时所有证书是否可用
+const ConditionTLSReady = "TLSReady"
+
 // StatefulSetPrefix used by statefulsets
 const StatefulSetPrefix = "ss"
 
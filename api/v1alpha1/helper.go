@@ -2,14 +2,19 @@ package v1alpha1
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	stderr "errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/json"
 
@@ -17,6 +22,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/minio/minio-go/v7/pkg/credentials"
 
@@ -49,9 +55,40 @@ func (m *MinIO) HasConfigurationSecret() bool {
 	return m.Spec.Configuration != nil && m.Spec.Configuration.Name != ""
 }
 
-// 查询设置的环境变量
+// 返回配置 Secret 的名称，未指定时使用由 operator 生成的 "MINIO名称-configuration"
+func (m *MinIO) ConfigurationSecretName() string {
+	if m.HasConfigurationSecret() {
+		return m.Spec.Configuration.Name
+	}
+	return m.Name + TenantConfigurationSecretSuffix
+}
+
+// Env 中不允许设置的 root 凭证环境变量，避免 Pod 与管理接口使用不同的凭证
+var rootCredentialEnvVars = map[string]bool{
+	"MINIO_ROOT_USER":     true,
+	"MINIO_ROOT_PASSWORD": true,
+	"MINIO_ACCESS_KEY":    true,
+	"MINIO_SECRET_KEY":    true,
+}
+
+// 查询设置的环境变量，root 凭证只由配置 Secret 的 config.env 提供，Env 中的同名变量被忽略
 func (m *MinIO) GetEnvVars() (env []corev1.EnvVar) {
-	return m.Spec.Env
+	for _, e := range m.Spec.Env {
+		if !rootCredentialEnvVars[e.Name] {
+			env = append(env, e)
+		}
+	}
+	return env
+}
+
+// 返回 Env 中被忽略的 root 凭证环境变量名称
+func (m *MinIO) IgnoredRootCredentialEnvVars() (names []string) {
+	for _, e := range m.Spec.Env {
+		if rootCredentialEnvVars[e.Name] {
+			names = append(names, e.Name)
+		}
+	}
+	return names
 }
 
 func (m *MinIO) NewMinIOAdmin(minioSecret map[string][]byte, tr *http.Transport) (*madmin.AdminClient, error) {
@@ -104,18 +141,77 @@ func (m *MinIO) MinIOServerHostAddress() string {
 	var port int
 
 	if m.TLS() {
-		port = MinIOPortSVC
-	} else {
 		port = MinIOTLSPortSVC
+	} else {
+		port = MinIOPortSVC
 	}
 
 	return net.JoinHostPort(m.MinIOFQDNServiceName(), strconv.Itoa(port))
 }
 
 func (m *MinIO) TLS() bool {
+	return m.AutoCert() || len(m.Spec.ExternalCertSecrets) > 0 || m.Spec.CertManager != nil
+}
+
+// 是否通过 CSR 自动签发证书
+func (m *MinIO) AutoCert() bool {
 	return m.Spec.EnableCert
 }
 
+// 返回 MinIO 使用的所有证书，第一个证书为默认证书，依次为自动签发的证书、cert-manager 签发的证书及外部提供的证书
+func (m *MinIO) CertSecrets() []ExternalCertSecret {
+	var secrets []ExternalCertSecret
+	if m.AutoCert() {
+		secrets = append(secrets, ExternalCertSecret{Name: m.TLSSecretName(), Type: CertSecretTypeTLS})
+	}
+	if m.Spec.CertManager != nil {
+		secrets = append(secrets, ExternalCertSecret{Name: m.CertManagerSecretName(), Type: CertSecretTypeCertManager})
+	}
+	for _, secret := range m.Spec.ExternalCertSecrets {
+		if secret.Type == "" {
+			secret.Type = CertSecretTypeTLS
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets
+}
+
+// 返回证书过期预警的阈值，从大到小排列
+func (m *MinIO) CertExpiryWarningThresholds() []time.Duration {
+	var thresholds []time.Duration
+	for _, d := range m.Spec.CertExpiryWarningThresholds {
+		thresholds = append(thresholds, d.Duration)
+	}
+	if len(thresholds) == 0 {
+		thresholds = append(thresholds, DefaultCertExpiryWarningThresholds...)
+	}
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] > thresholds[j] })
+	return thresholds
+}
+
+// 返回 cert-manager Certificate 及其签发的证书 Secret 的名称
+func (m *MinIO) CertManagerSecretName() string {
+	return m.Name + CertManagerSecretSuffix
+}
+
+// 校验 Secret 是否被 MinIO 实例用作配置或证书
+func (m *MinIO) ReferencesSecret(name string) bool {
+	if m.ConfigurationSecretName() == name {
+		return true
+	}
+	for _, secret := range m.CertSecrets() {
+		if secret.Name == name {
+			return true
+		}
+	}
+	for _, secret := range m.Spec.ExternalCACertSecrets {
+		if secret.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MinIO) MinIOFQDNServiceName() string {
 	return fmt.Sprintf("%s.%s.svc.%s", m.MinIOCIServiceName(), m.Namespace, GetClusterDomain())
 }
@@ -135,32 +231,223 @@ func (m *MinIO) MinIOConsoleServiceName() string {
 	return m.Name + "console"
 }
 
+// 返回保存 MinIO 证书的 Secret 名称
+func (m *MinIO) TLSSecretName() string {
+	return m.Name + TLSSecretSuffix
+}
+
+// 返回自动签发证书时使用的 CSR 名称，CSR 为集群级别的资源，名称中包含 namespace
+func (m *MinIO) CSRName() string {
+	return m.Name + "-" + m.Namespace + CSRNameSuffix
+}
+
+// 返回自动签发证书时临时保存私钥的 Secret 名称
+func (m *MinIO) CSRKeySecretName() string {
+	return m.Name + CSRNameSuffix
+}
+
+// 返回证书需要包含的域名: 所有 Pod 在 Headless Service 下的域名及 MinIO Service 的域名
+func (m *MinIO) CertificateDNSNames() []string {
+	var dnsNames []string
+	for i := range m.Spec.Pools {
+		pool := &m.Spec.Pools[i]
+		for j := 0; j < pool.Servers; j++ {
+			dnsNames = append(dnsNames, fmt.Sprintf("%s.%s.%s.svc.%s", m.PoolPodName(pool, j), m.MinIOHLServiceName(), m.Namespace, GetClusterDomain()))
+		}
+	}
+	return append(dnsNames,
+		m.MinIOCIServiceName(),
+		fmt.Sprintf("%s.%s", m.MinIOCIServiceName(), m.Namespace),
+		fmt.Sprintf("%s.%s.svc", m.MinIOCIServiceName(), m.Namespace),
+		m.MinIOFQDNServiceName(),
+	)
+}
+
 func (m *MinIO) DefaultPodEnv() []corev1.EnvVar {
 	var envVar []corev1.EnvVar
+	// 单盘部署不支持纠删码
+	if m.ErasureSetSize() < 2 {
+		return envVar
+	}
 	envVar = append(envVar, corev1.EnvVar{
 		Name:  "MINIO_STORAGE_CLASS_STANDARD",
-		Value: "EC:0",
+		Value: fmt.Sprintf("EC:%d", m.StandardParity()),
+	}, corev1.EnvVar{
+		Name:  "MINIO_STORAGE_CLASS_RRS",
+		Value: fmt.Sprintf("EC:%d", m.ReducedRedundancyParity()),
 	})
 
 	return envVar
 }
 
-func (m *MinIO) NewControllerRevision() *appsv1.ControllerRevision {
-	rawData, _ := json.Marshal(m.Spec)
+// 返回服务池的纠删码集合大小，与 MinIO 的计算方式一致:
+// 在 2 到 16 之间选择能整除服务池总盘数、且与服务器数量对称的最大值，不存在这样的值时 MinIO 无法启动
+func PoolErasureSetSize(pool *Pool) (int, error) {
+	drives := pool.Servers * pool.VolumesPerServer
+	if drives < 2 {
+		return drives, nil
+	}
+	for size := 16; size >= 2; size-- {
+		if drives%size != 0 {
+			continue
+		}
+		if size%pool.Servers == 0 || pool.Servers%size == 0 {
+			return size, nil
+		}
+	}
+	return 0, fmt.Errorf("%d drives of pool %s (%d servers x %d volumes) can not be divided into erasure sets of 2 to 16 drives symmetric across servers",
+		drives, pool.Name, pool.Servers, pool.VolumesPerServer)
+}
+
+// 返回所有服务池中最小的纠删码集合大小，校验盘数量受该值限制
+// 无法划分纠删码集合的服务池由 ValidateParity 拒绝，不参与计算
+func (m *MinIO) ErasureSetSize() int {
+	size := 0
+	for i := range m.Spec.Pools {
+		s, err := PoolErasureSetSize(&m.Spec.Pools[i])
+		if err != nil {
+			continue
+		}
+		if size == 0 || s < size {
+			size = s
+		}
+	}
+	return size
+}
+
+// 返回 STANDARD 存储类型的校验盘数量，未设置时与 MinIO 的默认值一致
+func (m *MinIO) StandardParity() int {
+	if m.Spec.StandardParity != nil {
+		return *m.Spec.StandardParity
+	}
+	switch size := m.ErasureSetSize(); {
+	case size < 2:
+		return 0
+	case size < 4:
+		return 1
+	case size < 6:
+		return 2
+	case size < 8:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// 返回 REDUCED_REDUNDANCY 存储类型的校验盘数量，未设置时为 1
+func (m *MinIO) ReducedRedundancyParity() int {
+	if m.Spec.ReducedRedundancyParity != nil {
+		return *m.Spec.ReducedRedundancyParity
+	}
+	if parity := m.StandardParity(); parity < 1 {
+		return parity
+	}
+	return 1
+}
+
+// 校验每个服务池能否划分纠删码集合，以及校验盘数量是否与纠删码集合大小匹配
+func (m *MinIO) ValidateParity() error {
+	standard := m.StandardParity()
+	rrs := m.ReducedRedundancyParity()
+	for i := range m.Spec.Pools {
+		pool := &m.Spec.Pools[i]
+		size, err := PoolErasureSetSize(pool)
+		if err != nil {
+			return err
+		}
+		if size < 2 {
+			if standard > 0 || rrs > 0 {
+				return fmt.Errorf("pool %s has only %d drive, erasure coding is not supported", pool.Name, size)
+			}
+			continue
+		}
+		if standard > size/2 {
+			return fmt.Errorf("standard parity %d exceeds half of the erasure set size %d of pool %s", standard, size, pool.Name)
+		}
+	}
+	if rrs > standard {
+		return fmt.Errorf("reduced redundancy parity %d exceeds standard parity %d", rrs, standard)
+	}
+	return nil
+}
+
+// 返回生效的纠删码配置
+func (m *MinIO) ErasureCodingStatus() *ErasureCodingStatus {
+	status := &ErasureCodingStatus{
+		ErasureSetSize:          m.ErasureSetSize(),
+		StandardParity:          m.StandardParity(),
+		ReducedRedundancyParity: m.ReducedRedundancyParity(),
+	}
+	status.ToleratedDriveFailures = status.StandardParity
+	for i := range m.Spec.Pools {
+		size, err := PoolErasureSetSize(&m.Spec.Pools[i])
+		if err != nil {
+			continue
+		}
+		if tolerated := toleratedDriveFailures(size, status.StandardParity); tolerated < status.ToleratedDriveFailures {
+			status.ToleratedDriveFailures = tolerated
+		}
+	}
+	return status
+}
+
+// 返回纠删码集合中损坏后仍可读写的盘数量
+// 写入需要 size-parity 块盘，校验盘恰好占一半时 MinIO 需要额外一块盘才能写入，可容忍的盘数量比校验盘少 1
+func toleratedDriveFailures(size, parity int) int {
+	if parity > 0 && parity*2 == size {
+		return parity - 1
+	}
+	return parity
+}
+
+// 根据当前规格生成 ControllerRevision，名称由规格内容的哈希值决定，相同的规格对应同一个 ControllerRevision
+func (m *MinIO) NewControllerRevision(revision int64) *appsv1.ControllerRevision {
+	rawData, _ := json.Marshal(m.RevisionSpec())
+	hash := sha256.Sum256(rawData)
 
 	cr := &appsv1.ControllerRevision{}
 	cr.Namespace = m.Namespace
-	cr.Name = m.Name
+	cr.Name = fmt.Sprintf("%s-%s", m.Name, hex.EncodeToString(hash[:])[:10])
+	cr.Labels = m.MinIOPodLabels()
+	cr.Labels[Revision] = strconv.FormatInt(revision, 10)
+	cr.Revision = revision
 	cr.Data.Raw = rawData
 
 	return cr
 }
 
+// 返回记录到 ControllerRevision 中的规格，不包含历史版本相关的配置
+func (m *MinIO) RevisionSpec() MinIOSpec {
+	spec := *m.Spec.DeepCopy()
+	spec.RevisionHistoryLimit = nil
+	return spec
+}
+
+// 返回需要保留的历史版本数量
+func (m *MinIO) RevisionHistoryLimit() int {
+	if m.Spec.RevisionHistoryLimit == nil {
+		return DefaultRevisionHistoryLimit
+	}
+	return int(*m.Spec.RevisionHistoryLimit)
+}
+
 // returns the Kubernetes cluster domain
 func GetClusterDomain() string {
 	return "cluster.local"
 }
 
+// 返回健康检查的间隔，由 MONITORING_INTERVAL 环境变量设置，整数表示分钟，也可使用 30s 等形式，默认为 DefaultMonitoringInterval 分钟
+func GetMonitoringInterval() time.Duration {
+	value := envGet(monitoringIntervalEnv, "")
+	if minutes, err := strconv.Atoi(value); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+		return interval
+	}
+	return DefaultMonitoringInterval * time.Minute
+}
+
 func envGet(key, defaultValue string) string {
 	if v, ok := os.LookupEnv(key); ok {
 		return v
@@ -175,6 +462,134 @@ func (m *MinIO) MinIOPodLabels() map[string]string {
 	return lbs
 }
 
+// 返回 MinIO 服务池中 Pod 的 Labels
+func (m *MinIO) MinIOPoolLabels(pool *Pool) map[string]string {
+	lbs := m.MinIOPodLabels()
+	lbs[PoolLabel] = pool.Name
+	return lbs
+}
+
+// 返回服务池对应的 StatefulSet 名称
+func (m *MinIO) PoolStatefulSetName(pool *Pool) string {
+	return fmt.Sprintf("%s-%s-%s", m.Name, StatefulSetPrefix, pool.Name)
+}
+
+// 根据 Pod 名称返回所属的服务池及 Pod 的序号
+func (m *MinIO) PoolServer(podName string) (*Pool, int, bool) {
+	for i := range m.Spec.Pools {
+		pool := &m.Spec.Pools[i]
+		prefix := m.PoolStatefulSetName(pool) + "-"
+		if !strings.HasPrefix(podName, prefix) {
+			continue
+		}
+		if index, err := strconv.Atoi(strings.TrimPrefix(podName, prefix)); err == nil {
+			return pool, index, true
+		}
+	}
+	return nil, 0, false
+}
+
+// 根据卷在 Pod 中的挂载路径返回卷的序号，与 Pod 模板中卷的挂载路径保持一致
+func (m *MinIO) PoolVolumeIndex(pool *Pool, path string) (int, bool) {
+	mountPath := m.MountPath()
+	if pool.VolumesPerServer == 1 {
+		return 0, path == mountPath
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(path, mountPath+"-"))
+	if err != nil || !strings.HasPrefix(path, mountPath+"-") || index >= pool.VolumesPerServer {
+		return 0, false
+	}
+	return index, true
+}
+
+// 返回服务池中第 index 个 Pod 的名称，同时也是 Pod 的 hostname
+func (m *MinIO) PoolPodName(pool *Pool, index int) string {
+	return fmt.Sprintf("%s-%d", m.PoolStatefulSetName(pool), index)
+}
+
+// 返回滚动更新时服务池最多不可用的 Pod 数量，至少为 1
+func (m *MinIO) PoolMaxUnavailable(pool *Pool) int {
+	maxUnavailable := 1
+	if m.Spec.MaxUnavailable != nil {
+		v, err := intstr.GetScaledValueFromIntOrPercent(m.Spec.MaxUnavailable, pool.Servers, false)
+		if err == nil {
+			maxUnavailable = v
+		}
+	}
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	return maxUnavailable
+}
+
+// 返回访问 MinIO 服务及 Console 使用的协议
+func (m *MinIO) Scheme() string {
+	if m.TLS() {
+		return "https"
+	}
+	return "http"
+}
+
+// 返回服务池在 MinIO 启动参数中的地址，使用省略号表示服务池中的所有 Pod 及卷，例如
+// http://minio-ss-pool-{0...3}.miniohl.default.svc.cluster.local/export-{0...3}
+// 只有一个 Pod 和一块卷时 MinIO 只接受单机模式的路径，此时返回卷的挂载路径
+func (m *MinIO) PoolEndpoint(pool *Pool) string {
+	if pool.Servers == 1 && pool.VolumesPerServer == 1 {
+		return m.MountPath()
+	}
+	scheme := m.Scheme()
+
+	host := m.PoolStatefulSetName(pool) + "-0"
+	if pool.Servers > 1 {
+		host = fmt.Sprintf("%s-{0...%d}", m.PoolStatefulSetName(pool), pool.Servers-1)
+	}
+	host = fmt.Sprintf("%s.%s.%s.svc.%s", host, m.MinIOHLServiceName(), m.Namespace, GetClusterDomain())
+
+	// 与 Pod 模板中卷的挂载路径保持一致
+	path := m.MountPath()
+	if pool.VolumesPerServer > 1 {
+		path = fmt.Sprintf("%s-{0...%d}", path, pool.VolumesPerServer-1)
+	}
+
+	return fmt.Sprintf("%s://%s%s", scheme, host, path)
+}
+
+// 校验服务池能否组成 MinIO 集群，多个服务池时每个服务池的地址都必须使用省略号
+func (m *MinIO) ValidateTopology() error {
+	if len(m.Spec.Pools) < 2 {
+		return nil
+	}
+	for _, pool := range m.Spec.Pools {
+		if pool.Servers == 1 && pool.VolumesPerServer == 1 {
+			return fmt.Errorf("pool %s has only 1 server with 1 volume, which can not be combined with other pools", pool.Name)
+		}
+	}
+	return nil
+}
+
+// 返回所有服务池的地址，按 Pools 中的顺序排列
+func (m *MinIO) ServerEndpoints() []string {
+	endpoints := make([]string, 0, len(m.Spec.Pools))
+	for i := range m.Spec.Pools {
+		endpoints = append(endpoints, m.PoolEndpoint(&m.Spec.Pools[i]))
+	}
+	return endpoints
+}
+
+// 返回所有服务池拓扑的哈希值
+func (m *MinIO) ServerTopologyHash() string {
+	sum := sha256.Sum256([]byte(strings.Join(m.ServerEndpoints(), " ")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// 返回卷的挂载路径，未设置时使用默认路径
+func (m *MinIO) MountPath() string {
+	if m.Spec.Mountpath == "" {
+		return MinIOVolumeMountPath
+	}
+	return strings.TrimRight(m.Spec.Mountpath, "/")
+}
+
 // 返回由 MinIO 纳管资源的 OwnerReference
 func (m *MinIO) OwnerRef() []metav1.OwnerReference {
 	return []metav1.OwnerReference{
@@ -194,22 +609,18 @@ func (m *MinIO) ExposeMinIOConsoleSvc() bool {
 	return m.Spec.ExposeServices.Console
 }
 
-// MinIO 服务健康检查
-func (m *MinIO) MinIOHealthCheck(tr *http.Transport) bool {
-	if tr.TLSClientConfig != nil {
-		tr.TLSClientConfig.InsecureSkipVerify = true
-	}
-
+// MinIO 服务健康检查，无法访问服务（如证书校验失败）时返回错误
+func (m *MinIO) MinIOHealthCheck(ctx context.Context, tr *http.Transport) (bool, error) {
 	clnt, err := madmin.NewAnonymousClient(m.MinIOServerHostAddress(), m.TLS())
 	if err != nil {
-		return false
+		return false, err
 	}
 	clnt.SetCustomTransport(tr)
 
-	result, err := clnt.Healthy(context.Background(), madmin.HealthOpts{})
+	result, err := clnt.Healthy(ctx, madmin.HealthOpts{})
 	if err != nil {
-		return false
+		return false, err
 	}
 
-	return result.Healthy
+	return result.Healthy, nil
 }
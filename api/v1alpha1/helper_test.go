@@ -0,0 +1,312 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestPoolErasureSetSize(t *testing.T) {
+	tests := []struct {
+		servers, volumes int
+		want             int
+		wantErr          bool
+	}{
+		{servers: 1, volumes: 1, want: 1},
+		{servers: 2, volumes: 1, want: 2},
+		{servers: 4, volumes: 1, want: 4},
+		{servers: 5, volumes: 1, want: 5},
+		{servers: 4, volumes: 4, want: 16},
+		{servers: 3, volumes: 4, want: 12},
+		{servers: 8, volumes: 4, want: 16},
+		{servers: 6, volumes: 3, want: 6},
+		{servers: 9, volumes: 2, want: 9},
+		{servers: 10, volumes: 2, want: 10},
+		{servers: 32, volumes: 1, want: 16},
+		// 大于 16 的质数无法划分
+		{servers: 17, volumes: 1, wantErr: true},
+		// 只有 2 能整除总盘数，但与服务器数量不对称
+		{servers: 17, volumes: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		pool := &Pool{Name: "pool-0", Servers: tt.servers, VolumesPerServer: tt.volumes}
+		got, err := PoolErasureSetSize(pool)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("PoolErasureSetSize(%d servers x %d volumes) error = %v, wantErr %v", tt.servers, tt.volumes, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("PoolErasureSetSize(%d servers x %d volumes) = %d, want %d", tt.servers, tt.volumes, got, tt.want)
+		}
+	}
+}
+
+func TestValidateParity(t *testing.T) {
+	tests := []struct {
+		name     string
+		pools    []Pool
+		standard *int
+		rrs      *int
+		wantErr  bool
+	}{
+		{
+			name:  "default parity",
+			pools: []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 4}},
+		},
+		{
+			name:  "single drive without erasure coding",
+			pools: []Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 1}},
+		},
+		{
+			name:     "single drive with parity",
+			pools:    []Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 1}},
+			standard: intPtr(1),
+			wantErr:  true,
+		},
+		{
+			name:     "parity equals half of the erasure set",
+			pools:    []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 1}},
+			standard: intPtr(2),
+		},
+		{
+			name:     "parity exceeds half of the erasure set",
+			pools:    []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 1}},
+			standard: intPtr(3),
+			wantErr:  true,
+		},
+		{
+			name: "parity exceeds half of the smallest pool",
+			pools: []Pool{
+				{Name: "pool-0", Servers: 4, VolumesPerServer: 4},
+				{Name: "pool-1", Servers: 4, VolumesPerServer: 1},
+			},
+			standard: intPtr(4),
+			wantErr:  true,
+		},
+		{
+			name: "pool can not be divided into erasure sets",
+			pools: []Pool{
+				{Name: "pool-0", Servers: 4, VolumesPerServer: 4},
+				{Name: "pool-1", Servers: 17, VolumesPerServer: 2},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "reduced redundancy parity exceeds standard parity",
+			pools:    []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 4}},
+			standard: intPtr(2),
+			rrs:      intPtr(3),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MinIO{Spec: MinIOSpec{Pools: tt.pools, StandardParity: tt.standard, ReducedRedundancyParity: tt.rrs}}
+			if err := m.ValidateParity(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateParity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestErasureCodingStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		pools    []Pool
+		standard *int
+		want     ErasureCodingStatus
+	}{
+		{
+			name:  "16 drives with default parity",
+			pools: []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 4}},
+			want:  ErasureCodingStatus{ErasureSetSize: 16, StandardParity: 4, ReducedRedundancyParity: 1, ToleratedDriveFailures: 4},
+		},
+		{
+			name:  "4 drives with default parity of half the erasure set",
+			pools: []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 1}},
+			want:  ErasureCodingStatus{ErasureSetSize: 4, StandardParity: 2, ReducedRedundancyParity: 1, ToleratedDriveFailures: 1},
+		},
+		{
+			name:     "8 drives with parity of half the erasure set",
+			pools:    []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 2}},
+			standard: intPtr(4),
+			want:     ErasureCodingStatus{ErasureSetSize: 8, StandardParity: 4, ReducedRedundancyParity: 1, ToleratedDriveFailures: 3},
+		},
+		{
+			name:  "2 drives",
+			pools: []Pool{{Name: "pool-0", Servers: 2, VolumesPerServer: 1}},
+			want:  ErasureCodingStatus{ErasureSetSize: 2, StandardParity: 1, ReducedRedundancyParity: 1, ToleratedDriveFailures: 0},
+		},
+		{
+			name:  "single drive",
+			pools: []Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 1}},
+			want:  ErasureCodingStatus{ErasureSetSize: 1, StandardParity: 0, ReducedRedundancyParity: 0, ToleratedDriveFailures: 0},
+		},
+		{
+			name: "parity of half the erasure set in the smaller pool only",
+			pools: []Pool{
+				{Name: "pool-0", Servers: 4, VolumesPerServer: 4},
+				{Name: "pool-1", Servers: 4, VolumesPerServer: 1},
+			},
+			want: ErasureCodingStatus{ErasureSetSize: 4, StandardParity: 2, ReducedRedundancyParity: 1, ToleratedDriveFailures: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MinIO{Spec: MinIOSpec{Pools: tt.pools, StandardParity: tt.standard}}
+			if got := m.ErasureCodingStatus(); *got != tt.want {
+				t.Errorf("ErasureCodingStatus() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertExpiryWarningThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds []metav1.Duration
+		want       []time.Duration
+	}{
+		{
+			name: "default thresholds",
+			want: []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour},
+		},
+		{
+			name:       "sorted from the largest",
+			thresholds: []metav1.Duration{{Duration: time.Hour}, {Duration: 14 * 24 * time.Hour}, {Duration: 3 * 24 * time.Hour}},
+			want:       []time.Duration{14 * 24 * time.Hour, 3 * 24 * time.Hour, time.Hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MinIO{Spec: MinIOSpec{CertExpiryWarningThresholds: tt.thresholds}}
+			if got := m.CertExpiryWarningThresholds(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CertExpiryWarningThresholds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 返回值排序不影响默认阈值
+	m := &MinIO{}
+	thresholds := m.CertExpiryWarningThresholds()
+	thresholds[0] = time.Minute
+	if DefaultCertExpiryWarningThresholds[0] != 30*24*time.Hour {
+		t.Errorf("default thresholds modified")
+	}
+}
+
+func TestGetMonitoringInterval(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", DefaultMonitoringInterval * time.Minute},
+		{"3", 3 * time.Minute},
+		{"30s", 30 * time.Second},
+		{"1h", time.Hour},
+		{"0", DefaultMonitoringInterval * time.Minute},
+		{"-1m", DefaultMonitoringInterval * time.Minute},
+		{"invalid", DefaultMonitoringInterval * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Setenv(monitoringIntervalEnv, tt.value)
+		if got := GetMonitoringInterval(); got != tt.want {
+			t.Errorf("GetMonitoringInterval() with %q = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestServerEndpoints(t *testing.T) {
+	tests := []struct {
+		name  string
+		pools []Pool
+		want  []string
+	}{
+		{
+			name:  "single server with single volume",
+			pools: []Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 1}},
+			want:  []string{"/export"},
+		},
+		{
+			name:  "single server with multiple volumes",
+			pools: []Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 4}},
+			want:  []string{"http://minio-ss-pool-0-0.miniohl.default.svc.cluster.local/export-{0...3}"},
+		},
+		{
+			name:  "multiple servers with single volume",
+			pools: []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 1}},
+			want:  []string{"http://minio-ss-pool-0-{0...3}.miniohl.default.svc.cluster.local/export"},
+		},
+		{
+			name: "multiple pools",
+			pools: []Pool{
+				{Name: "pool-0", Servers: 4, VolumesPerServer: 2},
+				{Name: "pool-1", Servers: 1, VolumesPerServer: 4},
+			},
+			want: []string{
+				"http://minio-ss-pool-0-{0...3}.miniohl.default.svc.cluster.local/export-{0...1}",
+				"http://minio-ss-pool-1-0.miniohl.default.svc.cluster.local/export-{0...3}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MinIO{
+				ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "default"},
+				Spec:       MinIOSpec{Pools: tt.pools},
+			}
+			if got := m.ServerEndpoints(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServerEndpoints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTopology(t *testing.T) {
+	tests := []struct {
+		name    string
+		pools   []Pool
+		wantErr bool
+	}{
+		{"single server with single volume", []Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 1}}, false},
+		{"multiple pools", []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 1}, {Name: "pool-1", Servers: 1, VolumesPerServer: 4}}, false},
+		{"single server with single volume in multiple pools", []Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 1}, {Name: "pool-1", Servers: 1, VolumesPerServer: 1}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MinIO{Spec: MinIOSpec{Pools: tt.pools}}
+			if err := m.ValidateTopology(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTopology() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetEnvVars(t *testing.T) {
+	m := &MinIO{Spec: MinIOSpec{Env: []corev1.EnvVar{
+		{Name: "MINIO_ROOT_USER", Value: "admin"},
+		{Name: "MINIO_BROWSER", Value: "off"},
+		{Name: "MINIO_SECRET_KEY", Value: "password"},
+		{Name: "MINIO_DOMAIN", Value: "example.com"},
+	}}}
+
+	want := []corev1.EnvVar{{Name: "MINIO_BROWSER", Value: "off"}, {Name: "MINIO_DOMAIN", Value: "example.com"}}
+	if got := m.GetEnvVars(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetEnvVars() = %v, want %v", got, want)
+	}
+	if got := m.IgnoredRootCredentialEnvVars(); !reflect.DeepEqual(got, []string{"MINIO_ROOT_USER", "MINIO_SECRET_KEY"}) {
+		t.Errorf("IgnoredRootCredentialEnvVars() = %v, want [MINIO_ROOT_USER MINIO_SECRET_KEY]", got)
+	}
+
+	m.Spec.Env = nil
+	if got := m.IgnoredRootCredentialEnvVars(); len(got) != 0 {
+		t.Errorf("IgnoredRootCredentialEnvVars() = %v, want none", got)
+	}
+}
@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -32,7 +33,8 @@ type MinIOSpec struct {
 	Image           string                      `json:"image"`
 	ImagePullPolicy corev1.PullPolicy           `json:"imagePullPolicy,omitempty"`
 	ImagePullSecret corev1.LocalObjectReference `json:"imagePullSecret,omitempty"`
-	Env             []corev1.EnvVar             `json:"env,omitempty"`
+	// MinIO 服务的环境变量，root 凭证只能通过配置 Secret 设置，MINIO_ROOT_USER 等变量会被忽略
+	Env []corev1.EnvVar `json:"env,omitempty"`
 	// 卷的挂载路径，默认为 /data
 	Mountpath string `json:"mountPath,omitempty"`
 	// MinIO 服务需要的配置,由 Secret 提供
@@ -40,9 +42,19 @@ type MinIOSpec struct {
 	// 是否暴露服务
 	ExposeServices ExposeServices `json:"exposeService,omitempty"`
 
-	// 是否启用 tls
+	// 是否启用 tls，为 true 时通过 CSR 自动签发证书
 	EnableCert bool `json:"enableCert,omitempty"`
-	// 是否删除PVC，如果为 true 则在同时删除 PVC
+	// 外部提供的证书，第一个证书作为默认证书（未开启 EnableCert 时），其他证书按 SNI 选择
+	ExternalCertSecrets []ExternalCertSecret `json:"externalCertSecrets,omitempty"`
+	// 外部提供的 CA 证书，用于信任客户端及其他服务的证书，Secret 中的 ca.crt
+	ExternalCACertSecrets []corev1.LocalObjectReference `json:"externalCaCertSecrets,omitempty"`
+	// 通过 cert-manager 签发证书
+	CertManager *CertManagerCertificate `json:"certManager,omitempty"`
+	// 健康检查及管理接口访问 MinIO 服务时不校验证书，仅用于测试环境
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// 证书剩余有效期低于这些阈值时产生 Warning 事件，默认为 720h、168h 和 24h
+	CertExpiryWarningThresholds []metav1.Duration `json:"certExpiryWarningThresholds,omitempty"`
+	// 是否删除PVC，如果为 true 则在同时删除 PVC，为 false 时保留 PVC 及自动生成的配置 Secret
 	ReclaimStorage bool `json:"reclaimStorage,omitempty"`
 
 	ServiceAccountName string                      `json:"serviceAccountName,omitempty"`
@@ -54,6 +66,19 @@ type MinIOSpec struct {
 	Readiness *corev1.Probe     `json:"readiness,omitempty"`
 	Startup   *corev1.Probe     `json:"startup,omitempty"`
 	Lifecycle *corev1.Lifecycle `json:"lifecycle,omitempty"`
+
+	// 滚动更新时每个服务池最多不可用的 Pod 数量或比例，默认为 1
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// 保留的历史版本数量，默认为 10
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// STANDARD 存储类型的校验盘数量，不能超过纠删码集合大小的一半，未设置时由纠删码集合大小决定
+	// +kubebuilder:validation:Minimum=0
+	StandardParity *int `json:"standardParity,omitempty"`
+	// REDUCED_REDUNDANCY 存储类型的校验盘数量，不能超过 StandardParity，未设置时为 1
+	// +kubebuilder:validation:Minimum=0
+	ReducedRedundancyParity *int `json:"reducedRedundancyParity,omitempty"`
 }
 
 // 服务池
@@ -62,13 +87,51 @@ type Pool struct {
 	Name string `json:"name"`
 	// 服务池需要启动MinIO服务的pod数量
 	Servers int `json:"servers"`
-	// 每个服务需要挂载的卷数量
+	// 每个服务需要挂载的卷数量，服务池部署后不可修改
 	VolumesPerServer int `json:"volumesPerServer"`
 	// 指定要使用的存储卷
 	VolumeClaimTemplate      *corev1.PersistentVolumeClaim `json:"volumeClaimTemplate"`
 	NodeSelector             map[string]string             `json:"nodeSelector,omitempty"`
 	SecurityContext          *corev1.PodSecurityContext    `json:"securityContext,omitempty"`
 	ContainerSecurityContext *corev1.SecurityContext       `json:"containerSecurityContext,omitempty"`
+	// 下线服务池，数据迁移到其他服务池后删除该服务池的 Pod 及 PVC
+	Decommission bool `json:"decommission,omitempty"`
+}
+
+// 证书 Secret 的类型，决定证书和私钥的 key
+type CertSecretType string
+
+const (
+	// 证书和私钥保存在 tls.crt 和 tls.key 中
+	CertSecretTypeTLS CertSecretType = "kubernetes.io/tls"
+	// 证书和私钥保存在 tls.crt 和 tls.key 中，签发者的 CA 证书保存在 ca.crt 中
+	CertSecretTypeCertManager CertSecretType = "cert-manager.io/v1"
+	// 证书和私钥保存在 public.crt 和 private.key 中
+	CertSecretTypeMinIO CertSecretType = "Opaque"
+)
+
+type ExternalCertSecret struct {
+	Name string `json:"name"`
+	// 默认为 kubernetes.io/tls
+	// +kubebuilder:validation:Enum=kubernetes.io/tls;cert-manager.io/v1;Opaque
+	Type CertSecretType `json:"type,omitempty"`
+}
+
+type CertManagerCertificate struct {
+	// 签发证书的 Issuer 或 ClusterIssuer
+	IssuerRef CertManagerIssuerRef `json:"issuerRef"`
+	// 证书有效期，默认由 cert-manager 决定
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// 证书到期前多久续期，默认由 cert-manager 决定
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+}
+
+type CertManagerIssuerRef struct {
+	Name string `json:"name"`
+	// Issuer 或 ClusterIssuer，默认为 Issuer
+	Kind string `json:"kind,omitempty"`
+	// 默认为 cert-manager.io
+	Group string `json:"group,omitempty"`
 }
 
 type ExposeServices struct {
@@ -78,20 +141,6 @@ type ExposeServices struct {
 	Console bool `json:"console,omitempty"`
 }
 
-// 整体部署状态
-type DeployStatus string
-
-const (
-	// 初始状态
-	DeployStatusNone DeployStatus = "None"
-	// 服务部署完成
-	DeployStatusCompleted DeployStatus = "Completed"
-	// 正在运行
-	DeployStatusRunning DeployStatus = "Running"
-	// 部署失败
-	DeployStatusFailed DeployStatus = "Failed"
-)
-
 // 服务池部署状态
 type PoolDeployStatus string
 
@@ -102,45 +151,171 @@ const (
 	PoolStatusRunning PoolDeployStatus = "Running"
 	// 资源池创建失败
 	PoolStatusFailed PoolDeployStatus = "Failed"
+	// 资源池正在滚动更新
+	PoolStatusUpdating PoolDeployStatus = "Updating"
 )
 
-// 服务健康状态
-type HealthStatus string
+// MinIOStatus defines the observed state of MinIO
+type MinIOStatus struct {
+	// 各 controller 通过 server-side apply 分别维护自己负责的字段:
+	// MinIOReconciler 负责版本、纠删码、证书、凭证轮换、服务池下线进度及 Progressing、Degraded、CredentialsReady、TLSReady 状态，
+	// MinIOStatusReconciler 负责服务池、PVC、Service 及 Available 状态，MinIOHealthCheckerReconciler 负责 Healthy 状态
+
+	// MinIOReconciler 最近一次处理的规格版本
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	PoolStatus []PoolStatus `json:"poolStatus,omitempty"`
+	// 服务访问地址
+	Service *MinIOServiceAddr `json:"service,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	PVCStatus []PVCStatus `json:"pvcStatus,omitempty"`
+	// 所有服务池已完成更新的版本
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// 当前规格对应的版本
+	UpdateRevision string `json:"updateRevision,omitempty"`
+	// 生效的纠删码配置
+	ErasureCoding *ErasureCodingStatus `json:"erasureCoding,omitempty"`
+	// 证书的有效期
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+	// root 凭证轮换状态
+	CredentialRotation *CredentialRotationStatus `json:"credentialRotation,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+type CertificateStatus struct {
+	// 证书所在的 Secret
+	SecretName string `json:"secretName"`
+	// 证书的 CommonName
+	Subject  string      `json:"subject,omitempty"`
+	NotAfter metav1.Time `json:"notAfter"`
+	// 已触发 Warning 事件的最小阈值
+	ExpiryWarning string `json:"expiryWarning,omitempty"`
+}
+
+// root 凭证轮换阶段
+type CredentialRotationPhase string
 
 const (
-	HealthStatusHealth   HealthStatus = "Health"
-	HealthStatusUnHealth HealthStatus = "UnHealth"
-	HealthStatusUnknown  HealthStatus = "Unknown"
+	// 已更新配置 Secret，等待所有 Pod 使用新凭证重启并验证
+	CredentialRotationRotating CredentialRotationPhase = "Rotating"
+	// 新凭证验证失败，已恢复原有凭证，等待所有 Pod 重启
+	CredentialRotationRollingBack CredentialRotationPhase = "RollingBack"
+	// 轮换成功
+	CredentialRotationSucceeded CredentialRotationPhase = "Succeeded"
+	// 轮换失败，已回滚到原有凭证
+	CredentialRotationFailed CredentialRotationPhase = "Failed"
 )
 
-// MinIOStatus defines the observed state of MinIO
-type MinIOStatus struct {
-	Status DeployStatus `json:"status"`
-	// 状态异常信息
-	Message      string       `json:"message"`
-	PoolStatus   []PoolStatus `json:"poolStatus"`
-	HealthStatus HealthStatus `json:"healthStatus"`
-	// 服务访问地址
-	Service   MinIOServiceAddr `json:"service"`
-	PVCStatus []PVCStatus      `json:"pvcStatus"`
+type CredentialRotationStatus struct {
+	Phase CredentialRotationPhase `json:"phase"`
+	// 已处理的 rotate-credentials 注解的值
+	ObservedRequest string `json:"observedRequest,omitempty"`
+	// 本次轮换开始的时间
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// 所有 Pod 使用新凭证重启并就绪、开始验证凭证的时间，验证超时从该时间开始计算
+	VerifyStartTime *metav1.Time `json:"verifyStartTime,omitempty"`
+	// 上次轮换成功的时间
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	Message          string       `json:"message,omitempty"`
+}
+
+type ErasureCodingStatus struct {
+	// 所有服务池中最小的纠删码集合大小
+	ErasureSetSize int `json:"erasureSetSize"`
+	// STANDARD 存储类型的校验盘数量
+	StandardParity int `json:"standardParity"`
+	// REDUCED_REDUNDANCY 存储类型的校验盘数量
+	ReducedRedundancyParity int `json:"reducedRedundancyParity"`
+	// 每个纠删码集合中损坏后 STANDARD 存储类型的对象仍可读写的盘数量，
+	// 校验盘恰好占纠删码集合一半时比 StandardParity 少 1，此时损坏 StandardParity 块盘仍可读取但无法写入
+	ToleratedDriveFailures int `json:"toleratedDriveFailures"`
 }
 
 type PoolStatus struct {
 	// MinIO 服务池名称
 	Name              string           `json:"name"`
-	Status            PoolDeployStatus `json:"status"`
-	AvailableReplicas int              `json:"availableReplicas"`
-	Replicas          int              `json:"replicas"`
+	Status            PoolDeployStatus `json:"status,omitempty"`
+	AvailableReplicas int              `json:"availableReplicas,omitempty"`
+	Replicas          int              `json:"replicas,omitempty"`
+	// 滚动更新进度，已更新到最新版本的 Pod 数量
+	UpdatedReplicas int    `json:"updatedReplicas,omitempty"`
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	UpdateRevision  string `json:"updateRevision,omitempty"`
+	// 序号大于等于 Partition 的 Pod 允许更新
+	Partition int `json:"partition,omitempty"`
 	// 服务状态
-	Servers []MinIOServer `json:"servers"`
+	// +listType=map
+	// +listMapKey=name
+	Servers []MinIOServer `json:"servers,omitempty"`
+	// 服务池下线进度
+	Decommission *PoolDecommissionStatus `json:"decommission,omitempty"`
+}
+
+// 服务池下线状态
+type DecommissionStatus string
+
+const (
+	// 等待开始下线
+	DecommissionPending DecommissionStatus = "Pending"
+	// 正在迁移数据
+	DecommissionDraining DecommissionStatus = "Draining"
+	// 数据迁移完成
+	DecommissionComplete DecommissionStatus = "Complete"
+	// 数据迁移失败
+	DecommissionFailed DecommissionStatus = "Failed"
+	// 数据迁移被取消
+	DecommissionCanceled DecommissionStatus = "Canceled"
+)
+
+type PoolDecommissionStatus struct {
+	Status    DecommissionStatus `json:"status"`
+	StartTime *metav1.Time       `json:"startTime,omitempty"`
+	// 开始下线时服务池的总容量和当前剩余容量，单位为字节
+	TotalSize   int64 `json:"totalSize,omitempty"`
+	CurrentSize int64 `json:"currentSize,omitempty"`
+	// 开始下线时服务池中的数据量
+	StartSize int64  `json:"startSize,omitempty"`
+	Message   string `json:"message,omitempty"`
 }
 
 // MinIO 服务状态
 type MinIOServer struct {
 	Name   string `json:"name"`
-	HostIP string `json:"hostIP"`
-	PodIP  string `json:"podIP"`
-	Status string `json:"status"`
+	HostIP string `json:"hostIP,omitempty"`
+	PodIP  string `json:"podIP,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// 以下字段由健康检查通过管理接口获取
+	// 服务状态，online 或 offline
+	State string `json:"state,omitempty"`
+	// 运行时间，单位为秒
+	Uptime int64 `json:"uptime,omitempty"`
+	// MinIO 版本
+	Version string `json:"version,omitempty"`
+	// +listType=map
+	// +listMapKey=path
+	Drives []DriveStatus `json:"drives,omitempty"`
+}
+
+// 服务挂载的卷的状态
+type DriveStatus struct {
+	// 卷在 Pod 中的挂载路径
+	Path string `json:"path"`
+	// 卷对应的 PVC
+	PVC string `json:"pvc,omitempty"`
+	// 卷状态，如 ok、offline、faulty
+	State string `json:"state,omitempty"`
+	// 总容量和已用容量，单位为字节
+	TotalSpace int64 `json:"totalSpace,omitempty"`
+	UsedSpace  int64 `json:"usedSpace,omitempty"`
+	// 是否正在修复数据
+	Healing bool `json:"healing,omitempty"`
 }
 
 // MinIO 访问地址，包括服务地址和 Console 地址
@@ -155,12 +330,34 @@ type PVCStatus struct {
 	Volume       string `json:"volume"`
 	Capacity     string `json:"capacity"`
 	StorageClass string `json:"storageClass"`
+	// PVC 请求的容量，扩容过程中大于 Capacity
+	RequestedCapacity string `json:"requestedCapacity,omitempty"`
+	// 扩容状态
+	ResizeStatus PVCResizeStatus `json:"resizeStatus,omitempty"`
+	// 无法绑定、扩容失败等异常信息
+	Message string `json:"message,omitempty"`
 }
 
+// PVC 扩容状态
+type PVCResizeStatus string
+
+const (
+	// 服务池模板请求的容量大于 PVC，等待扩容
+	PVCResizePending PVCResizeStatus = "Pending"
+	// 存储卷正在扩容
+	PVCResizing PVCResizeStatus = "Resizing"
+	// 存储卷扩容完成，等待扩展文件系统
+	PVCFileSystemResizePending PVCResizeStatus = "FileSystemResizePending"
+	// 扩容失败
+	PVCResizeFailed PVCResizeStatus = "Failed"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Namespaced,shortName=minio
-// +kubebuilder:printcolumn:name="status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="healthy",type=string,JSONPath=`.status.conditions[?(@.type=="Healthy")].status`
+// +kubebuilder:printcolumn:name="progressing",type=string,JSONPath=`.status.conditions[?(@.type=="Progressing")].status`
 // +kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // MinIO is the Schema for the minios API
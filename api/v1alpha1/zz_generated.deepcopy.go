@@ -23,9 +23,125 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerCertificate) DeepCopyInto(out *CertManagerCertificate) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerCertificate.
+func (in *CertManagerCertificate) DeepCopy() *CertManagerCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerIssuerRef) DeepCopyInto(out *CertManagerIssuerRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerRef.
+func (in *CertManagerIssuerRef) DeepCopy() *CertManagerIssuerRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerIssuerRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateStatus) DeepCopyInto(out *CertificateStatus) {
+	*out = *in
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateStatus.
+func (in *CertificateStatus) DeepCopy() *CertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialRotationStatus) DeepCopyInto(out *CredentialRotationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.VerifyStartTime != nil {
+		in, out := &in.VerifyStartTime, &out.VerifyStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialRotationStatus.
+func (in *CredentialRotationStatus) DeepCopy() *CredentialRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriveStatus) DeepCopyInto(out *DriveStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriveStatus.
+func (in *DriveStatus) DeepCopy() *DriveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DriveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErasureCodingStatus) DeepCopyInto(out *ErasureCodingStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErasureCodingStatus.
+func (in *ErasureCodingStatus) DeepCopy() *ErasureCodingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ErasureCodingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExposeServices) DeepCopyInto(out *ExposeServices) {
 	*out = *in
@@ -41,6 +157,21 @@ func (in *ExposeServices) DeepCopy() *ExposeServices {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalCertSecret) DeepCopyInto(out *ExternalCertSecret) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalCertSecret.
+func (in *ExternalCertSecret) DeepCopy() *ExternalCertSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalCertSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MinIO) DeepCopyInto(out *MinIO) {
 	*out = *in
@@ -103,6 +234,11 @@ func (in *MinIOList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MinIOServer) DeepCopyInto(out *MinIOServer) {
 	*out = *in
+	if in.Drives != nil {
+		in, out := &in.Drives, &out.Drives
+		*out = make([]DriveStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinIOServer.
@@ -154,6 +290,26 @@ func (in *MinIOSpec) DeepCopyInto(out *MinIOSpec) {
 		**out = **in
 	}
 	out.ExposeServices = in.ExposeServices
+	if in.ExternalCertSecrets != nil {
+		in, out := &in.ExternalCertSecrets, &out.ExternalCertSecrets
+		*out = make([]ExternalCertSecret, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExternalCACertSecrets != nil {
+		in, out := &in.ExternalCACertSecrets, &out.ExternalCACertSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(CertManagerCertificate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CertExpiryWarningThresholds != nil {
+		in, out := &in.CertExpiryWarningThresholds, &out.CertExpiryWarningThresholds
+		*out = make([]metav1.Duration, len(*in))
+		copy(*out, *in)
+	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
 		*out = make([]v1.Toleration, len(*in))
@@ -187,6 +343,26 @@ func (in *MinIOSpec) DeepCopyInto(out *MinIOSpec) {
 		*out = new(v1.Lifecycle)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.StandardParity != nil {
+		in, out := &in.StandardParity, &out.StandardParity
+		*out = new(int)
+		**out = **in
+	}
+	if in.ReducedRedundancyParity != nil {
+		in, out := &in.ReducedRedundancyParity, &out.ReducedRedundancyParity
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinIOSpec.
@@ -209,12 +385,40 @@ func (in *MinIOStatus) DeepCopyInto(out *MinIOStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.Service = in.Service
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(MinIOServiceAddr)
+		**out = **in
+	}
 	if in.PVCStatus != nil {
 		in, out := &in.PVCStatus, &out.PVCStatus
 		*out = make([]PVCStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.ErasureCoding != nil {
+		in, out := &in.ErasureCoding, &out.ErasureCoding
+		*out = new(ErasureCodingStatus)
+		**out = **in
+	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make([]CertificateStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CredentialRotation != nil {
+		in, out := &in.CredentialRotation, &out.CredentialRotation
+		*out = new(CredentialRotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinIOStatus.
@@ -279,13 +483,39 @@ func (in *Pool) DeepCopy() *Pool {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PoolDecommissionStatus) DeepCopyInto(out *PoolDecommissionStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PoolDecommissionStatus.
+func (in *PoolDecommissionStatus) DeepCopy() *PoolDecommissionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PoolDecommissionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PoolStatus) DeepCopyInto(out *PoolStatus) {
 	*out = *in
 	if in.Servers != nil {
 		in, out := &in.Servers, &out.Servers
 		*out = make([]MinIOServer, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Decommission != nil {
+		in, out := &in.Decommission, &out.Decommission
+		*out = new(PoolDecommissionStatus)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// 证书的过期时间，通过 manager 的 metrics 接口暴露
+	certificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "minio_operator",
+		Name:      "certificate_expiry_timestamp_seconds",
+		Help:      "The notAfter time of certificates used by MinIO instances, in seconds since epoch.",
+	}, []string{"namespace", "minio", "secret"})
+
+	// 提交状态时与其他 field manager 发生冲突的次数
+	statusConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "minio_operator",
+		Name:      "status_update_conflicts_total",
+		Help:      "The number of field manager conflicts when applying the status of MinIO instances, by field manager.",
+	}, []string{"field_manager"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(certificateExpiry, statusConflicts)
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// 开启 TLS 时通过 CSR 自动签发证书并保存到 "MINIO名称-tls" Secret 中，返回 true 表示证书 Secret 已可用
+// 私钥在签发期间临时保存在 "MINIO名称-csr" Secret 中，不阻塞调谐，通过 DefaultQueryInterval 定期查询签发结果
+// 服务池变化导致域名列表变化或证书剩余有效期不足三分之一时重新签发，签发完成前 Pod 继续使用原有证书
+func (r *MinIOReconciler) checkAutoCert(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+	if !minio.AutoCert() {
+		return true, nil
+	}
+
+	dnsHash := utils.CertificateDNSHash(minio)
+	tlsSecret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.TLSSecretName(), metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	exists := err == nil
+	if exists && tlsSecret.Annotations[miniov1alpha1.CertificateDNSHashAnnotation] == dnsHash {
+		cert, err := utils.ParseCertificate(tlsSecret.Data[corev1.TLSCertKey])
+		if err == nil && !utils.CertificateNeedsRenewal(cert, time.Now()) {
+			return true, nil
+		}
+	}
+
+	csrClient := r.KubeClient.CertificatesV1().CertificateSigningRequests()
+	csr, err := csrClient.Get(ctx, minio.CSRName(), metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	// 域名列表已变化的 CSR 需要重新提交
+	if err == nil && csr.Annotations[miniov1alpha1.CertificateDNSHashAnnotation] != dnsHash {
+		if err := csrClient.Delete(ctx, csr.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		return exists, nil
+	}
+
+	if errors.IsNotFound(err) {
+		if exists {
+			r.Recorder.Eventf(minio, corev1.EventTypeNormal, "CertificateRenewing", "Renewing certificate in Secret %s", minio.TLSSecretName())
+		}
+		if err := r.submitCSR(ctx, minio); err != nil {
+			return false, err
+		}
+		setTLSCondition(minio, metav1.ConditionFalse, "CertificatePending", fmt.Sprintf("Waiting for CertificateSigningRequest %s to be issued", minio.CSRName()))
+		return exists, nil
+	}
+
+	for _, cond := range csr.Status.Conditions {
+		if cond.Type == certificatesv1.CertificateDenied || cond.Type == certificatesv1.CertificateFailed {
+			message := fmt.Sprintf("CertificateSigningRequest %s %s, %s", csr.Name, cond.Type, cond.Message)
+			if c := meta.FindStatusCondition(minio.Status.Conditions, miniov1alpha1.ConditionTLSReady); c == nil || c.Reason != "CertificateDenied" {
+				r.Recorder.Event(minio, corev1.EventTypeWarning, "CertificateDenied", message)
+			}
+			setTLSCondition(minio, metav1.ConditionFalse, "CertificateDenied", message)
+			return exists, nil
+		}
+	}
+
+	if len(csr.Status.Certificate) == 0 {
+		// 超时未签发时删除 CSR，下次调谐时重新提交
+		if time.Since(csr.CreationTimestamp.Time) > miniov1alpha1.DefaultQueryTimeout {
+			r.Recorder.Eventf(minio, corev1.EventTypeWarning, "CSRTimeout", "CertificateSigningRequest %s not issued in %s, resubmitting", csr.Name, miniov1alpha1.DefaultQueryTimeout)
+			if err := csrClient.Delete(ctx, csr.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return exists, nil
+	}
+
+	// 证书已签发，与私钥一起保存到证书 Secret 中
+	keySecret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.CSRKeySecretName(), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// 私钥丢失，重新提交 CSR
+			return exists, csrClient.Delete(ctx, csr.Name, metav1.DeleteOptions{})
+		}
+		return false, err
+	}
+	expected := utils.NewTLSSecretForMinIO(minio, csr.Status.Certificate, keySecret.Data[corev1.TLSPrivateKeyKey], dnsHash)
+	if exists {
+		tlsSecret.Annotations = expected.Annotations
+		tlsSecret.Data = expected.Data
+		_, err = r.KubeClient.CoreV1().Secrets(minio.Namespace).Update(ctx, tlsSecret, metav1.UpdateOptions{})
+	} else {
+		_, err = r.KubeClient.CoreV1().Secrets(minio.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+	}
+	if err != nil {
+		klog.Errorf("save certificate Secret %s/%s error, %s", minio.Namespace, minio.TLSSecretName(), err)
+		return false, err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "CertificateIssued", "Certificate issued and stored in Secret %s", minio.TLSSecretName())
+
+	if err := csrClient.Delete(ctx, csr.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return true, err
+	}
+	if err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Delete(ctx, keySecret.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// 生成私钥并提交 CSR，CSR 由 operator 批准后等待签发
+func (r *MinIOReconciler) submitCSR(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	keySecret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.CSRKeySecretName(), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		keyPEM, err := utils.NewPrivateKey()
+		if err != nil {
+			klog.Errorf("generate private key for MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+			return err
+		}
+		keySecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            minio.CSRKeySecretName(),
+				Namespace:       minio.Namespace,
+				Labels:          minio.MinIOPodLabels(),
+				OwnerReferences: minio.OwnerRef(),
+			},
+			Data: map[string][]byte{
+				corev1.TLSPrivateKeyKey: keyPEM,
+			},
+		}
+		if keySecret, err = r.KubeClient.CoreV1().Secrets(minio.Namespace).Create(ctx, keySecret, metav1.CreateOptions{}); err != nil {
+			klog.Errorf("create Secret %s/%s error, %s", minio.Namespace, minio.CSRKeySecretName(), err)
+			return err
+		}
+	}
+
+	csrPEM, err := utils.NewCertificateRequest(minio, keySecret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		klog.Errorf("generate certificate request for MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+		return err
+	}
+	csr, err := r.KubeClient.CertificatesV1().CertificateSigningRequests().Create(ctx, utils.NewCertificateSigningRequestForMinIO(minio, csrPEM), metav1.CreateOptions{})
+	if err != nil {
+		klog.Errorf("create CertificateSigningRequest %s error, %s", minio.CSRName(), err)
+		return err
+	}
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           certificatesv1.CertificateApproved,
+		Status:         corev1.ConditionTrue,
+		Reason:         "MinIOOperatorApprove",
+		Message:        "Automatically approved by MinIO Operator",
+		LastUpdateTime: metav1.Now(),
+	})
+	if _, err := r.KubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("approve CertificateSigningRequest %s error, %s", csr.Name, err)
+		return err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "CSRCreated", "CertificateSigningRequest %s created and approved", csr.Name)
+
+	return nil
+}
+
+// 删除 MinIO 实例时清理集群级别的 CSR
+func (r *MinIOReconciler) deleteCSR(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	err := r.KubeClient.CertificatesV1().CertificateSigningRequests().Delete(ctx, minio.CSRName(), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("delete CertificateSigningRequest %s error, %s", minio.CSRName(), err)
+		return err
+	}
+	return nil
+}
+
+// 设置 TLSReady 状态
+func setTLSCondition(minio *miniov1alpha1.MinIO, status metav1.ConditionStatus, reason, message string) {
+	setCondition(minio, miniov1alpha1.ConditionTLSReady, status, reason, message)
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 返回所有证书及 CA 证书 Secret 内容的哈希值，证书续期后 Pod 模板随之变化
+// 任意证书 Secret 不存在时将 CertificateReady 设置为 False 并返回错误
+func (r *MinIOReconciler) certificateHash(ctx context.Context, minio *miniov1alpha1.MinIO) (string, error) {
+	var names []string
+	for _, secret := range minio.CertSecrets() {
+		names = append(names, secret.Name)
+	}
+	for _, secret := range minio.Spec.ExternalCACertSecrets {
+		names = append(names, secret.Name)
+	}
+
+	data := make(map[string]map[string][]byte, len(names))
+	for _, name := range names {
+		secret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			message := fmt.Sprintf("Certificate Secret %s not available, %s", name, err)
+			if c := meta.FindStatusCondition(minio.Status.Conditions, miniov1alpha1.ConditionTLSReady); c == nil || c.Message != message {
+				r.Recorder.Event(minio, corev1.EventTypeWarning, "CertificateNotFound", message)
+			}
+			setTLSCondition(minio, metav1.ConditionFalse, "CertificateNotFound", message)
+			return "", err
+		}
+		data[name] = secret.Data
+	}
+	setTLSCondition(minio, metav1.ConditionTrue, "CertificatesReady", fmt.Sprintf("Certificates loaded from Secrets %v", names))
+
+	return utils.ComputeHash(data), nil
+}
+
+// 设置 CertManager 时创建或更新 cert-manager 的 Certificate，返回 true 表示签发的证书 Secret 已存在
+// 为避免引入 cert-manager 的依赖，Certificate 以 unstructured 的方式创建
+func (r *MinIOReconciler) checkCertManagerCertificate(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+	if minio.Spec.CertManager == nil {
+		return true, nil
+	}
+
+	expected := utils.NewCertManagerCertificateForMinIO(minio)
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(expected.GroupVersionKind())
+	err := r.Get(ctx, client.ObjectKeyFromObject(expected), found)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Errorf("get Certificate %s/%s error, %s", expected.GetNamespace(), expected.GetName(), err)
+			return false, err
+		}
+		if err := r.Create(ctx, expected); err != nil {
+			klog.Errorf("create Certificate %s/%s error, %s", expected.GetNamespace(), expected.GetName(), err)
+			return false, err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "CertificateCreated", "cert-manager Certificate %s created", expected.GetName())
+	} else if !equality.Semantic.DeepEqual(found.Object["spec"], expected.Object["spec"]) {
+		found.Object["spec"] = expected.Object["spec"]
+		if err := r.Update(ctx, found); err != nil {
+			klog.Errorf("update Certificate %s/%s error, %s", found.GetNamespace(), found.GetName(), err)
+			return false, err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "CertificateUpdated", "cert-manager Certificate %s updated", found.GetName())
+	}
+
+	_, err = r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.CertManagerSecretName(), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			setTLSCondition(minio, metav1.ConditionFalse, "CertificatePending", fmt.Sprintf("Waiting for cert-manager to issue Secret %s", minio.CertManagerSecretName()))
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// 解析 MinIO 使用的证书，在状态中记录有效期并更新 Prometheus 指标，剩余有效期低于阈值时产生 Warning 事件
+func (r *MinIOReconciler) checkCertificateExpiry(ctx context.Context, minio *miniov1alpha1.MinIO) {
+	previous := make(map[string]miniov1alpha1.CertificateStatus, len(minio.Status.Certificates))
+	for _, status := range minio.Status.Certificates {
+		previous[status.SecretName] = status
+	}
+
+	var statuses []miniov1alpha1.CertificateStatus
+	thresholds := minio.CertExpiryWarningThresholds()
+	for _, secret := range minio.CertSecrets() {
+		s, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			klog.Errorf("get certificate Secret %s/%s error, %s", minio.Namespace, secret.Name, err)
+			continue
+		}
+		cert, err := utils.ParseCertificate(s.Data[utils.CertificateKey(secret)])
+		if err != nil {
+			r.Recorder.Eventf(minio, corev1.EventTypeWarning, "CertificateInvalid", "Certificate in Secret %s can not be parsed, %s", secret.Name, err)
+			continue
+		}
+
+		status := miniov1alpha1.CertificateStatus{
+			SecretName: secret.Name,
+			Subject:    cert.Subject.CommonName,
+			NotAfter:   metav1.NewTime(cert.NotAfter),
+		}
+		remaining := time.Until(cert.NotAfter)
+		for _, threshold := range thresholds {
+			if remaining < threshold {
+				status.ExpiryWarning = threshold.String()
+			}
+		}
+		if status.ExpiryWarning != "" && status.ExpiryWarning != previous[secret.Name].ExpiryWarning {
+			if remaining <= 0 {
+				r.Recorder.Eventf(minio, corev1.EventTypeWarning, "CertificateExpired", "Certificate in Secret %s expired at %s", secret.Name, cert.NotAfter.Format(time.RFC3339))
+			} else {
+				r.Recorder.Eventf(minio, corev1.EventTypeWarning, "CertificateExpiring", "Certificate in Secret %s expires at %s, less than %s left", secret.Name, cert.NotAfter.Format(time.RFC3339), status.ExpiryWarning)
+			}
+		}
+		certificateExpiry.WithLabelValues(minio.Namespace, minio.Name, secret.Name).Set(float64(cert.NotAfter.Unix()))
+		statuses = append(statuses, status)
+		delete(previous, secret.Name)
+	}
+
+	// 不再使用的证书
+	for name := range previous {
+		certificateExpiry.DeleteLabelValues(minio.Namespace, minio.Name, name)
+	}
+	minio.Status.Certificates = statuses
+}
+
+// 删除 MinIO 实例或关闭 TLS 时清理证书的 Prometheus 指标
+func clearCertificateExpiry(minio *miniov1alpha1.MinIO) {
+	for _, status := range minio.Status.Certificates {
+		certificateExpiry.DeleteLabelValues(minio.Namespace, minio.Name, status.SecretName)
+	}
+	minio.Status.Certificates = nil
+}
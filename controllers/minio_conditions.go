@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 设置状态，同时记录所基于的规格版本
+func setCondition(minio *miniov1alpha1.MinIO, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&minio.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: minio.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// 返回指定类型的状态
+func filterConditions(conditions []metav1.Condition, types ...string) []metav1.Condition {
+	var filtered []metav1.Condition
+	for _, cond := range conditions {
+		for _, t := range types {
+			if cond.Type == t {
+				filtered = append(filtered, cond)
+				break
+			}
+		}
+	}
+	return filtered
+}
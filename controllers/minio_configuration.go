@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	stderr "errors"
+	"fmt"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// 未指定配置 Secret 时生成包含随机 root 用户名和密码的配置 Secret
+func (r *MinIOReconciler) checkConfigurationSecret(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	if minio.HasConfigurationSecret() {
+		return nil
+	}
+
+	found, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.ConfigurationSecretName(), metav1.GetOptions{})
+	if err == nil {
+		return r.reuseConfigurationSecret(ctx, minio, found)
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	secret, err := utils.NewConfigurationSecretForMinIO(minio)
+	if err != nil {
+		klog.Errorf("generate credentials for MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+		return err
+	}
+	if _, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		klog.Errorf("create configuration Secret %s/%s error, %s", secret.Namespace, secret.Name, err)
+		return err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ConfigurationCreated", "Configuration Secret %s with generated root credentials created", secret.Name)
+
+	return nil
+}
+
+// 复用删除同名 MinIO 实例时保留下来的配置 Secret，保留的 PVC 中的数据仍使用原有的 root 凭证
+func (r *MinIOReconciler) reuseConfigurationSecret(ctx context.Context, minio *miniov1alpha1.MinIO, secret *corev1.Secret) error {
+	if _, orphaned := secret.Labels[miniov1alpha1.OrphanedLabel]; !orphaned {
+		return nil
+	}
+	delete(secret.Labels, miniov1alpha1.OrphanedLabel)
+	for k, v := range minio.MinIOPodLabels() {
+		secret.Labels[k] = v
+	}
+	secret.OwnerReferences = append(secret.OwnerReferences, minio.OwnerRef()...)
+	if _, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("reuse configuration Secret %s/%s error, %s", secret.Namespace, secret.Name, err)
+		return err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ConfigurationReused", "Orphaned configuration Secret %s reused", secret.Name)
+	return nil
+}
+
+// 校验配置 Secret 中的 root 用户名和密码并设置 CredentialsReady 状态
+func (r *MinIOReconciler) setCredentialsCondition(ctx context.Context, minio *miniov1alpha1.MinIO) {
+	condition := metav1.Condition{
+		Type:               miniov1alpha1.ConditionCredentialsReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: minio.Generation,
+		Reason:             "CredentialsResolved",
+		Message:            fmt.Sprintf("Root credentials resolved from Secret %s", minio.ConfigurationSecretName()),
+	}
+
+	_, err := getMinIOCredentials(ctx, r.KubeClient, minio)
+	switch {
+	case err == nil:
+	case stderr.Is(err, ErrEmptyRootCredentials):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CredentialsMissing"
+		condition.Message = fmt.Sprintf("MINIO_ROOT_USER or MINIO_ROOT_PASSWORD not set in config.env of Secret %s", minio.ConfigurationSecretName())
+	case stderr.Is(err, ErrMalformedRootCredentials):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CredentialsMalformed"
+		condition.Message = err.Error()
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ConfigurationNotFound"
+		condition.Message = fmt.Sprintf("Secret %s not available, %s", minio.ConfigurationSecretName(), err)
+	}
+
+	if condition.Status == metav1.ConditionFalse && !meta.IsStatusConditionPresentAndEqual(minio.Status.Conditions, condition.Type, condition.Status) {
+		r.Recorder.Event(minio, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+	meta.SetStatusCondition(&minio.Status.Conditions, condition)
+}
+
+// 返回配置 Secret 中 config.env 的哈希值
+func (r *MinIOReconciler) configurationHash(ctx context.Context, minio *miniov1alpha1.MinIO) (string, error) {
+	secret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.ConfigurationSecretName(), metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("get configuration Secret %s/%s error, %s", minio.Namespace, minio.ConfigurationSecretName(), err)
+		return "", err
+	}
+	return utils.ComputeHash(secret.Data["config.env"]), nil
+}
+
+// 根据 Secret 找到引用它作为配置或证书的 MinIO 实例
+func (r *MinIOReconciler) minioForSecret(obj client.Object) []reconcile.Request {
+	var minioList miniov1alpha1.MinIOList
+	if err := r.List(context.Background(), &minioList, client.InNamespace(obj.GetNamespace())); err != nil {
+		klog.Errorf("query MinIO list error, %s", err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, minio := range minioList.Items {
+		if minio.ReferencesSecret(obj.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: minio.Namespace, Name: minio.Name},
+			})
+		}
+	}
+	return requests
+}
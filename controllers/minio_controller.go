@@ -21,16 +21,17 @@ import (
 	stderr "errors"
 	"fmt"
 	"minio-operator/utils"
-	"reflect"
-
-	"k8s.io/apimachinery/pkg/util/json"
+	"strings"
+	"time"
 
 	"k8s.io/klog/v2"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 
 	"k8s.io/client-go/kubernetes"
@@ -40,9 +41,13 @@ import (
 	miniov1alpha1 "minio-operator/api/v1alpha1"
 
 	"k8s.io/apimachinery/pkg/runtime"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 var (
@@ -51,20 +56,61 @@ var (
 
 const (
 	OPNamespace = "operator"
+
+	// 等待服务池迁移完成的重试间隔
+	migrationRequeueInterval = 5 * time.Second
+
+	// 滚动更新过程中检查 Pod 就绪及集群健康状态的间隔
+	rolloutRequeueInterval = 10 * time.Second
+
+	// 检查证书有效期的间隔
+	certificateCheckInterval = time.Hour
 )
 
 // MinIOReconciler reconciles a MinIO object
 type MinIOReconciler struct {
 	client.Client
-	KubeClient *kubernetes.Clientset
+	KubeClient kubernetes.Interface
 	Scheme     *runtime.Scheme
 
 	Recorder record.EventRecorder
+
+	// 同时部署的 MinIO 实例数量，由 --minio-concurrency 设置
+	MaxConcurrentReconciles int
+
+	statusWriter *statusWriter
+	// 滚动更新等操作前检查 MinIO 集群是否健康，未设置时通过 MinIO 的健康检查接口确认
+	healthCheck func(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error)
+	// 下线服务池、验证凭证时访问的 MinIO 管理接口地址，未设置时使用 MinIO Service 的地址
+	adminAddress string
+}
+
+// 检查 MinIO 集群是否健康
+func (r *MinIOReconciler) minioHealthy(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+	if r.healthCheck != nil {
+		return r.healthCheck(ctx, minio)
+	}
+	return minioHealthCheck(ctx, r.KubeClient, minio)
 }
 
 // +kubebuilder:rbac:groups=minio.bob.com,resources=minios,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=minio.bob.com,resources=minios/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=minio.bob.com,resources=minios/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests/approval,verbs=update
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=signers,resourceNames=kubernetes.io/kubelet-serving,verbs=approve
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -81,47 +127,71 @@ func (r *MinIOReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		if errors.IsNotFound(err) {
 			return ctrl.Result{}, nil
 		}
+		return ctrl.Result{}, err
 	}
 
-	// 忽略删除中的资源
+	// 删除中的资源需要先停止服务并处理 PVC
 	if !minio.DeletionTimestamp.IsZero() {
-		utilruntime.HandleError(fmt.Errorf("MinIO '%s' is marked for deletion, skipping", minio.GetName()))
-		return ctrl.Result{}, nil
+		return r.handleDeletion(ctx, &minio)
 	}
 
-	// 默认为 None 状态
-	if minio.Status.Status == "" {
-		minio.Status.Status = miniov1alpha1.DeployStatusNone
-		err := r.updateMinIOStatusWithRetry(ctx, &minio, true)
-		if err != nil {
+	// 添加 finalizer 后会重新调谐
+	added, err := r.ensureFinalizer(ctx, &minio)
+	if err != nil || added {
+		return ctrl.Result{}, err
+	}
+
+	// 首次调谐时标记为正在部署
+	if meta.FindStatusCondition(minio.Status.Conditions, miniov1alpha1.ConditionProgressing) == nil {
+		setCondition(&minio, miniov1alpha1.ConditionProgressing, metav1.ConditionTrue, "Deploying", "Deploying MinIO")
+		if err := r.statusWriter.Apply(ctx, &minio); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	// 创建或更新对应的 ControllerRevision
-	var oldMinio miniov1alpha1.MinIO
-	newCr := minio.NewControllerRevision()
-	exist, cr, err := utils.ExistControllerRevision(minio.Name, minio.Namespace, r.Client)
+	// 回滚到指定的历史版本，规格更新后会重新调谐
+	if revision, ok := minio.Annotations[miniov1alpha1.RollbackToAnnotation]; ok {
+		return ctrl.Result{}, r.rollback(ctx, &minio, revision)
+	}
+
+	// 记录当前规格对应的 ControllerRevision
+	updateRevision, err := r.syncControllerRevisions(ctx, &minio)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	if !exist {
-		if err := utils.CreateControllerRevision(&minio, newCr, r.Client, r.Scheme); err != nil {
-			return ctrl.Result{}, err
-		}
-	} else {
-		if err := json.Unmarshal(cr.Data.Raw, &oldMinio.Spec); err != nil {
-			klog.Errorf("unmarshal ControllerRevision %s/%s error, %s", minio.Namespace, minio.Name, err)
+	if minio.Status.UpdateRevision != updateRevision {
+		minio.Status.UpdateRevision = updateRevision
+		if err := r.statusWriter.Apply(ctx, &minio); err != nil {
 			return ctrl.Result{}, err
 		}
-		if !reflect.DeepEqual(oldMinio.Spec, minio.Spec) {
-			if err := utils.DeleteControllerRevision(cr, r.Client); err != nil {
-				return ctrl.Result{}, err
-			}
-			if err := utils.CreateControllerRevision(&minio, newCr, r.Client, r.Scheme); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
+	}
+
+	// 移除或标记为下线的服务池在数据迁移完成前仍需部署，之后的调谐均基于实际部署的服务池
+	pools, err := effectivePools(ctx, r.Client, r.KubeClient, &minio)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	minio.Spec.Pools = pools
+	clearPoolDecommissionStatus(&minio)
+
+	// 服务池地址无法组成集群时 MinIO 无法启动，不再继续更新
+	if err := minio.ValidateTopology(); err != nil {
+		r.Recorder.Eventf(&minio, corev1.EventTypeWarning, "InvalidTopology", "Invalid pool topology, %s", err)
+		setCondition(&minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "InvalidTopology", err.Error())
+		return ctrl.Result{}, r.statusWriter.Apply(ctx, &minio)
+	}
+
+	// 校验盘数量与纠删码集合大小不匹配时 MinIO 无法启动，不再继续更新
+	if err := minio.ValidateParity(); err != nil {
+		r.Recorder.Eventf(&minio, corev1.EventTypeWarning, "InvalidErasureCoding", "Invalid erasure coding config, %s", err)
+		setCondition(&minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "InvalidErasureCoding", err.Error())
+		return ctrl.Result{}, r.statusWriter.Apply(ctx, &minio)
+	}
+	minio.Status.ErasureCoding = minio.ErasureCodingStatus()
+
+	// 管理接口只使用 config.env 中的凭证，Env 中的 root 凭证不会传给 Pod，仅在 spec 变化时提示一次
+	if ignored := minio.IgnoredRootCredentialEnvVars(); len(ignored) > 0 && minio.Generation != minio.Status.ObservedGeneration {
+		r.Recorder.Eventf(&minio, corev1.EventTypeWarning, "RootCredentialsInEnv", "Env %s ignored, set root credentials in config.env of Secret %s", strings.Join(ignored, ", "), minio.ConfigurationSecretName())
 	}
 
 	// 校验是否需要生成或更新 Service
@@ -139,106 +209,217 @@ func (r *MinIOReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
-	// 校验是否需要生成 PVC
-	if err := r.checkPVC(ctx, &minio); err != nil {
+	// 未指定配置 Secret 时生成 root 用户名和密码
+	if err := r.checkConfigurationSecret(ctx, &minio); err != nil {
 		return ctrl.Result{}, err
 	}
+	// 按 rotate-credentials 注解轮换 root 凭证
+	if err := r.startCredentialRotation(ctx, &minio); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.setCredentialsCondition(ctx, &minio)
 
-	var addPools []miniov1alpha1.Pool
-	var updatePools []miniov1alpha1.Pool
-
-	for _, newPool := range minio.Spec.Pools {
-		add := true
-		for _, oldPool := range oldMinio.Spec.Pools {
-			if newPool.Name == oldPool.Name {
-				add = false
-				break
-			}
-		}
-		if add {
-			addPools = append(addPools, newPool)
-		}
+	// 开启 TLS 时需要等待证书签发后再部署服务池
+	certReady, err := r.checkAutoCert(ctx, &minio)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
-	for _, oldPool := range oldMinio.Spec.Pools {
-		update := false
-		for _, newPool := range minio.Spec.Pools {
-			if newPool.Name == oldPool.Name {
-				if utils.MinioServerNeedToUpdate(oldMinio, minio, oldPool, newPool) {
-					update = true
-				}
-			}
+	if certReady {
+		certReady, err = r.checkCertManagerCertificate(ctx, &minio)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
-		if update {
-			updatePools = append(updatePools, oldPool)
+	}
+	if !certReady {
+		if err := r.statusWriter.Apply(ctx, &minio); err != nil {
+			return ctrl.Result{}, err
 		}
+		return ctrl.Result{RequeueAfter: miniov1alpha1.DefaultQueryInterval}, nil
 	}
 
-	var addPods []corev1.Pod
-	for _, pool := range addPools {
-		pods := utils.NewPodsForMinIOPool(ctx, minio, pool)
-		addPods = append(addPods, pods...)
+	// 配置 Secret 或证书的内容变化时需要滚动更新
+	configHash, err := r.configurationHash(ctx, &minio)
+	if err != nil {
+		if err := r.statusWriter.Apply(ctx, &minio); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
 	}
-	if len(addPods) > 0 {
-		for _, pod := range addPods {
-			if _, err := r.KubeClient.CoreV1().Pods(minio.Namespace).Create(ctx, &pod, metav1.CreateOptions{}); err != nil {
-				minio.Status.Status = miniov1alpha1.DeployStatusFailed
-				minio.Status.Message = fmt.Sprintf("MinIO Pod Create Failed, %s", err.Error())
-				if err := r.updateMinIOStatusWithRetry(ctx, &minio, true); err != nil {
-					return ctrl.Result{}, err
-				}
+	podAnnotations := map[string]string{
+		miniov1alpha1.ConfigurationHashAnnotation: configHash,
+	}
+	if minio.TLS() {
+		certHash, err := r.certificateHash(ctx, &minio)
+		if err != nil {
+			if err := r.statusWriter.Apply(ctx, &minio); err != nil {
 				return ctrl.Result{}, err
 			}
+			return ctrl.Result{RequeueAfter: miniov1alpha1.DefaultQueryInterval}, nil
 		}
+		podAnnotations[miniov1alpha1.CertificateHashAnnotation] = certHash
+		r.checkCertificateExpiry(ctx, &minio)
+	} else {
+		meta.RemoveStatusCondition(&minio.Status.Conditions, miniov1alpha1.ConditionTLSReady)
+		clearCertificateExpiry(&minio)
 	}
 
-	var updatePods []corev1.Pod
-	for _, pool := range updatePools {
-		pods := utils.NewPodsForMinIOPool(ctx, minio, pool)
-		updatePods = append(updatePods, pods...)
+	// MinIO 不支持修改已有服务池的卷数量，拒绝该变更且不重启任何服务池
+	if msg, err := r.checkPoolVolumes(ctx, &minio); err != nil || msg != "" {
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if previous := meta.FindStatusCondition(minio.Status.Conditions, miniov1alpha1.ConditionDegraded); previous == nil || previous.Message != msg {
+			r.Recorder.Event(&minio, corev1.EventTypeWarning, "VolumeCountChangeRejected", msg)
+		}
+		setCondition(&minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "VolumeCountChangeRejected", msg)
+		return ctrl.Result{}, r.statusWriter.Apply(ctx, &minio)
 	}
-	if len(updatePods) > 0 {
-		for _, pod := range updatePods {
-			if _, err := r.KubeClient.CoreV1().Pods(minio.Namespace).Update(ctx, &pod, metav1.UpdateOptions{}); err != nil {
-				minio.Status.Status = miniov1alpha1.DeployStatusFailed
-				minio.Status.Message = fmt.Sprintf("MinIO Pod Update Failed, %s", err.Error())
-				if err := r.updateMinIOStatusWithRetry(ctx, &minio, true); err != nil {
-					return ctrl.Result{}, err
-				}
+
+	// 服务池拓扑或 root 凭证变化时所有服务池需要同时重启
+	rolling, err := r.restartForTopologyChange(ctx, &minio, podAnnotations, credentialRotationInProgress(&minio))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 每个服务池由一个 StatefulSet 管理
+	migrating := false
+	decommissioning := false
+	for i := range minio.Spec.Pools {
+		pool := &minio.Spec.Pools[i]
+
+		// 直接创建 Pod 部署的服务池需要先完成迁移
+		migrated, err := r.migrateLegacyPool(ctx, &minio, pool)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !migrated {
+			migrating = true
+			continue
+		}
+
+		// 校验是否需要生成 PVC
+		if err := r.checkPVC(ctx, &minio, pool); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		changed, err := r.checkStatefulSet(ctx, &minio, pool, podAnnotations)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// StatefulSet 刚创建或更新时 Pod 尚未就绪，下次调谐时再校验
+		if changed {
+			rolling = true
+			continue
+		}
+		if err := r.checkPoolPods(ctx, &minio, pool); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		done, err := r.rolloutStatefulSet(ctx, &minio, pool)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !done {
+			rolling = true
+			continue
+		}
+
+		// 数据迁移完成前保留该服务池，规格中的变更暂不生效
+		if pool.Decommission {
+			decommissioned, err := r.decommissionPool(ctx, &minio, pool)
+			if err != nil {
 				return ctrl.Result{}, err
 			}
+			if !decommissioned {
+				decommissioning = true
+			}
+			continue
+		}
+
+		resizing, err := r.restartPodsForResize(ctx, &minio, pool)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if resizing {
+			rolling = true
+		}
+	}
+
+	// 所有 Pod 使用新凭证重启后验证 root 凭证
+	rotating := false
+	if !migrating && !rolling {
+		rotating, err = r.verifyCredentialRotation(ctx, &minio)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
 	}
 
-	minio.Status.Status = miniov1alpha1.DeployStatusRunning
-	if err := r.updateMinIOStatusWithRetry(ctx, &minio, true); err != nil {
+	// 根据本次调谐的进度设置 Progressing 状态，所有服务池都已完成更新时记录当前版本
+	switch {
+	case migrating:
+		setCondition(&minio, miniov1alpha1.ConditionProgressing, metav1.ConditionTrue, "Migrating", "Migrating pools to StatefulSets")
+	case rolling:
+		setCondition(&minio, miniov1alpha1.ConditionProgressing, metav1.ConditionTrue, "RollingUpdate", fmt.Sprintf("Rolling out revision %s", updateRevision))
+	case decommissioning:
+		setCondition(&minio, miniov1alpha1.ConditionProgressing, metav1.ConditionTrue, "Decommissioning", "Decommissioning pools")
+	case rotating:
+		setCondition(&minio, miniov1alpha1.ConditionProgressing, metav1.ConditionTrue, "RotatingCredentials", "Verifying rotated root credentials")
+	default:
+		minio.Status.CurrentRevision = updateRevision
+		setCondition(&minio, miniov1alpha1.ConditionProgressing, metav1.ConditionFalse, "RolloutComplete", fmt.Sprintf("All pools are updated to revision %s", updateRevision))
+	}
+	setCondition(&minio, miniov1alpha1.ConditionDegraded, metav1.ConditionFalse, "ReconcileSucceeded", "")
+	minio.Status.ObservedGeneration = minio.Generation
+	if err := r.statusWriter.Apply(ctx, &minio); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if migrating {
+		return ctrl.Result{RequeueAfter: migrationRequeueInterval}, nil
+	}
+	if rolling {
+		return ctrl.Result{RequeueAfter: rolloutRequeueInterval}, nil
+	}
+	if rotating {
+		return ctrl.Result{RequeueAfter: credentialRotationRequeueInterval}, nil
+	}
+	if decommissioning {
+		return ctrl.Result{RequeueAfter: decommissionRequeueInterval}, nil
+	}
+	// 定期检查证书有效期
+	if minio.TLS() {
+		return ctrl.Result{RequeueAfter: certificateCheckInterval}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
-// 更新 MinIO 实例状态
-func (r *MinIOReconciler) updateMinIOStatusWithRetry(ctx context.Context, minio *miniov1alpha1.MinIO, retry bool) error {
-	// if minio.Status.Status == miniov1alpha1.DeployStatusCompleted && minio.Status.AvailableReplicas == minio.Spec.Servers {
-	// 	return nil
-	// }
-
-	minioCopy := minio.DeepCopy()
-	minioCopy.Spec = miniov1alpha1.MinIOSpec{}
-	minioCopy.Status = minio.Status
-
-	if err := r.Status().Update(ctx, minioCopy); err != nil {
-		if errors.IsConflict(err) && retry {
-			klog.Infof("Hit conflict issue, getting latest version of MinIO %s", minio.Name)
-			err = r.Get(ctx, client.ObjectKeyFromObject(minio), minioCopy)
-			if err != nil {
-				return err
-			}
-			return r.updateMinIOStatusWithRetry(ctx, minioCopy, retry)
+// MinIOReconciler 负责的状态字段
+func minioStatusFields(status *miniov1alpha1.MinIOStatus) miniov1alpha1.MinIOStatus {
+	fields := miniov1alpha1.MinIOStatus{
+		ObservedGeneration: status.ObservedGeneration,
+		CurrentRevision:    status.CurrentRevision,
+		UpdateRevision:     status.UpdateRevision,
+		ErasureCoding:      status.ErasureCoding,
+		Certificates:       status.Certificates,
+		CredentialRotation: status.CredentialRotation,
+		Conditions: filterConditions(status.Conditions,
+			miniov1alpha1.ConditionProgressing,
+			miniov1alpha1.ConditionDegraded,
+			miniov1alpha1.ConditionCredentialsReady,
+			miniov1alpha1.ConditionTLSReady),
+	}
+	// 服务池的其他状态由 MinIOStatusReconciler 维护
+	for _, ps := range status.PoolStatus {
+		if ps.Decommission != nil {
+			fields.PoolStatus = append(fields.PoolStatus, miniov1alpha1.PoolStatus{
+				Name:         ps.Name,
+				Decommission: ps.Decommission,
+			})
 		}
-		return err
 	}
-	return nil
+	return fields
 }
 
 // 校验是否需要创建或更新 MinIO Service
@@ -246,17 +427,12 @@ func (r *MinIOReconciler) checkMinIOSvc(ctx context.Context, minio *miniov1alpha
 	svc, err := r.KubeClient.CoreV1().Services(minio.Namespace).Get(ctx, minio.MinIOCIServiceName(), metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			minio.Status.Status = miniov1alpha1.DeployStatusRunning
-			if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
-				return err
-			}
 			klog.V(2).Infof("Creating a new Cluster IP Service %s/%s", minio.Namespace, minio.MinIOCIServiceName())
 			svc = utils.NewServiceForMinIO(minio)
 			svc, err = r.KubeClient.CoreV1().Services(minio.Namespace).Create(ctx, svc, metav1.CreateOptions{})
 			if err != nil {
-				minio.Status.Status = miniov1alpha1.DeployStatusFailed
-				minio.Status.Message = "MinIO Service Create Failed"
-				if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
+				setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "ServiceFailed", fmt.Sprintf("MinIO Service Create Failed, %s", err))
+				if err := r.statusWriter.Apply(ctx, minio); err != nil {
 					return err
 				}
 				return err
@@ -277,13 +453,12 @@ func (r *MinIOReconciler) checkMinIOSvc(ctx context.Context, minio *miniov1alpha
 		}
 		svc.Annotations = expectedSvc.Annotations
 		svc.Labels = expectedSvc.Labels
-		svc.Spec = expectedSvc.Spec
+		utils.SyncServiceSpec(svc, expectedSvc)
 
 		_, err = r.KubeClient.CoreV1().Services(minio.Namespace).Update(ctx, svc, metav1.UpdateOptions{})
 		if err != nil {
-			minio.Status.Status = miniov1alpha1.DeployStatusFailed
-			minio.Status.Message = "MinIO Service Update Failed"
-			if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
+			setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "ServiceFailed", fmt.Sprintf("MinIO Service Update Failed, %s", err))
+			if err := r.statusWriter.Apply(ctx, minio); err != nil {
 				return err
 			}
 			return err
@@ -299,17 +474,12 @@ func (r *MinIOReconciler) checkConsoleSvc(ctx context.Context, minio *miniov1alp
 	svc, err := r.KubeClient.CoreV1().Services(minio.Namespace).Get(ctx, minio.MinIOConsoleServiceName(), metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			minio.Status.Status = miniov1alpha1.DeployStatusRunning
-			if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
-				return err
-			}
 			klog.V(2).Infof("Creating a new Console Service %s/%s", minio.Namespace, minio.MinIOConsoleServiceName())
 			svc = utils.NewConsoleServiceForMinIO(minio)
 			svc, err = r.KubeClient.CoreV1().Services(minio.Namespace).Create(ctx, svc, metav1.CreateOptions{})
 			if err != nil {
-				minio.Status.Status = miniov1alpha1.DeployStatusFailed
-				minio.Status.Message = "MinIO Console Service Create Failed"
-				if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
+				setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "ServiceFailed", fmt.Sprintf("MinIO Console Service Create Failed, %s", err))
+				if err := r.statusWriter.Apply(ctx, minio); err != nil {
 					return err
 				}
 				return err
@@ -328,13 +498,12 @@ func (r *MinIOReconciler) checkConsoleSvc(ctx context.Context, minio *miniov1alp
 		}
 		svc.Annotations = expectedSvc.Annotations
 		svc.Labels = expectedSvc.Labels
-		svc.Spec = expectedSvc.Spec
+		utils.SyncServiceSpec(svc, expectedSvc)
 
 		_, err = r.KubeClient.CoreV1().Services(minio.Namespace).Update(ctx, svc, metav1.UpdateOptions{})
 		if err != nil {
-			minio.Status.Status = miniov1alpha1.DeployStatusFailed
-			minio.Status.Message = "MinIO Console Service Created Failed"
-			if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
+			setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "ServiceFailed", fmt.Sprintf("MinIO Console Service Update Failed, %s", err))
+			if err := r.statusWriter.Apply(ctx, minio); err != nil {
 				return err
 			}
 			return err
@@ -350,17 +519,12 @@ func (r *MinIOReconciler) checkMinIOHLSvc(ctx context.Context, minio *miniov1alp
 	svc, err := r.KubeClient.CoreV1().Services(minio.Namespace).Get(ctx, minio.MinIOHLServiceName(), metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			minio.Status.Status = miniov1alpha1.DeployStatusRunning
-			if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
-				return err
-			}
-			klog.V(2).Infof("Creating a new Console Service %s/%s", minio.Namespace, minio.MinIOConsoleServiceName())
+			klog.V(2).Infof("Creating a new Headless Service %s/%s", minio.Namespace, minio.MinIOHLServiceName())
 			svc = utils.NewHeadlessServiceForMinIO(minio)
 			svc, err = r.KubeClient.CoreV1().Services(minio.Namespace).Create(ctx, svc, metav1.CreateOptions{})
 			if err != nil {
-				minio.Status.Status = miniov1alpha1.DeployStatusFailed
-				minio.Status.Message = "MinIO Headless Service Created Failed"
-				if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
+				setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "ServiceFailed", fmt.Sprintf("MinIO Headless Service Create Failed, %s", err))
+				if err := r.statusWriter.Apply(ctx, minio); err != nil {
 					return err
 				}
 				return err
@@ -371,68 +535,265 @@ func (r *MinIOReconciler) checkMinIOHLSvc(ctx context.Context, minio *miniov1alp
 		}
 	}
 
-	expectedSvc := utils.NewConsoleServiceForMinIO(minio)
+	expectedSvc := utils.NewHeadlessServiceForMinIO(minio)
+	if utils.ServiceNeedsRecreate(svc, expectedSvc) {
+		klog.Infof("MinIO Headless Service %s/%s has clusterIP %s, recreating", svc.Namespace, svc.Name, svc.Spec.ClusterIP)
+		if err := r.KubeClient.CoreV1().Services(minio.Namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &svc.UID}}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if _, err := r.KubeClient.CoreV1().Services(minio.Namespace).Create(ctx, expectedSvc, metav1.CreateOptions{}); err != nil {
+			setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "ServiceFailed", fmt.Sprintf("MinIO Headless Service Create Failed, %s", err))
+			if err := r.statusWriter.Apply(ctx, minio); err != nil {
+				return err
+			}
+			return err
+		}
+		r.Recorder.Event(minio, corev1.EventTypeNormal, "HLSvcRecreated", "MinIO Headless Service recreated without clusterIP")
+		return nil
+	}
+
 	isMatch, err := utils.MinioSvcMatchesSpecification(svc, expectedSvc)
 	if !isMatch {
 		if err != nil {
-			klog.Infof("MinIO Console Services don't match: %s", err)
+			klog.Infof("MinIO Headless Services don't match: %s", err)
 		}
 		svc.Annotations = expectedSvc.Annotations
 		svc.Labels = expectedSvc.Labels
-		svc.Spec = expectedSvc.Spec
+		utils.SyncServiceSpec(svc, expectedSvc)
 
 		_, err = r.KubeClient.CoreV1().Services(minio.Namespace).Update(ctx, svc, metav1.UpdateOptions{})
 		if err != nil {
-			minio.Status.Status = miniov1alpha1.DeployStatusFailed
-			minio.Status.Message = "MinIO Headless Service Created Failed"
-			if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
+			setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "ServiceFailed", fmt.Sprintf("MinIO Headless Service Update Failed, %s", err))
+			if err := r.statusWriter.Apply(ctx, minio); err != nil {
 				return err
 			}
 			return err
 		}
-		r.Recorder.Event(minio, corev1.EventTypeNormal, "SvcUpdated", "MinIO Headless Service Updated")
+		r.Recorder.Event(minio, corev1.EventTypeNormal, "HLSvcUpdated", "MinIO Headless Service Updated")
 	}
 
 	return nil
 }
 
-// 校验是否安装了 PVC
-func (r *MinIOReconciler) checkPVC(ctx context.Context, minio *miniov1alpha1.MinIO) error {
-	var pvcList corev1.PersistentVolumeClaimList
-	if err := r.List(ctx, &pvcList, client.InNamespace(minio.Namespace), client.MatchingLabels{miniov1alpha1.MinIOLable: minio.Name}); err != nil {
-		klog.Errorf("query PVC list error")
+// 校验是否需要创建或更新服务池的 StatefulSet
+// 返回 true 表示 StatefulSet 在本次调谐中被创建或更新
+func (r *MinIOReconciler) checkStatefulSet(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, podAnnotations map[string]string) (bool, error) {
+	expectedSs := utils.NewStatefulSetForMinIOPool(minio, pool, podAnnotations)
+
+	ss, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Get(ctx, expectedSs.Name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+		// StatefulSet 被以 Orphan 方式删除后重新创建时，接管的 Pod 同样由 rolloutStatefulSet 分批更新
+		var podList corev1.PodList
+		if err := r.List(ctx, &podList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPoolLabels(pool))); err != nil {
+			klog.Errorf("query Pod list of pool %s error, %s", pool.Name, err)
+			return false, err
+		}
+		if len(podList.Items) > 0 {
+			partition := *expectedSs.Spec.Replicas
+			expectedSs.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+		}
+		klog.V(2).Infof("Creating a new StatefulSet %s/%s", minio.Namespace, expectedSs.Name)
+		if _, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Create(ctx, expectedSs, metav1.CreateOptions{}); err != nil {
+			setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "StatefulSetFailed", fmt.Sprintf("MinIO StatefulSet Create Failed, %s", err))
+			if err := r.statusWriter.Apply(ctx, minio); err != nil {
+				return false, err
+			}
+			return false, err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "StatefulSetCreated", "MinIO StatefulSet %s Created", expectedSs.Name)
+		return true, nil
+	}
+
+	// 通过 Pod 模板的哈希值判断是否需要滚动更新
+	needUpdate := utils.PodTemplateHash(&ss.Spec.Template) != utils.PodTemplateHash(&expectedSs.Spec.Template)
+	if !needUpdate && *ss.Spec.Replicas == *expectedSs.Spec.Replicas {
+		return false, nil
+	}
+
+	// volumeClaimTemplates 等字段不可修改，只更新副本数和 Pod 模板
+	ss.Labels = expectedSs.Labels
+	ss.Spec.Replicas = expectedSs.Spec.Replicas
+	if needUpdate {
+		// 暂停 StatefulSet 自身的滚动更新，由 rolloutStatefulSet 分批推进，已不可用的旧 Pod 会被优先更新
+		partition := *expectedSs.Spec.Replicas
+		ss.Spec.Template = expectedSs.Spec.Template
+		ss.Spec.UpdateStrategy = expectedSs.Spec.UpdateStrategy
+		ss.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	}
+	if _, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+		setCondition(minio, miniov1alpha1.ConditionDegraded, metav1.ConditionTrue, "StatefulSetFailed", fmt.Sprintf("MinIO StatefulSet Update Failed, %s", err))
+		if err := r.statusWriter.Apply(ctx, minio); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "StatefulSetUpdated", "MinIO StatefulSet %s Updated", ss.Name)
+
+	return true, nil
+}
+
+// 按 PoolLabel 和序号校验服务池中的 Pod 是否完整
+// 缺失或失败的 Pod 由 StatefulSet 按原序号重建，保持相同的 hostname、subdomain 及 PVC 绑定
+func (r *MinIOReconciler) checkPoolPods(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) error {
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPoolLabels(pool))); err != nil {
+		klog.Errorf("query Pod list of pool %s error, %s", pool.Name, err)
 		return err
 	}
-	if len(pvcList.Items) == 0 {
-		minio.Status.Status = miniov1alpha1.DeployStatusRunning
-		if err := r.updateMinIOStatusWithRetry(ctx, minio, true); err != nil {
+	pods := make(map[string]*corev1.Pod, len(podList.Items))
+	for i := range podList.Items {
+		pods[podList.Items[i].Name] = &podList.Items[i]
+	}
+
+	// 状态中记录的 Pod，只在 Pod 由存在变为缺失时产生事件
+	previous := make(map[string]bool)
+	for _, ps := range minio.Status.PoolStatus {
+		if ps.Name != pool.Name {
+			continue
+		}
+		for _, s := range ps.Servers {
+			previous[s.Name] = s.Status != ""
+		}
+	}
+
+	for i := 0; i < pool.Servers; i++ {
+		name := minio.PoolPodName(pool, i)
+		pod, ok := pods[name]
+		if !ok {
+			if previous[name] {
+				r.Recorder.Eventf(minio, corev1.EventTypeWarning, "ServerPodMissing", "MinIO Pod %s of pool %s is missing, it will be recreated", name, pool.Name)
+			}
+			continue
+		}
+
+		if pod.DeletionTimestamp.IsZero() {
+			if pod.Status.Phase != corev1.PodFailed {
+				continue
+			}
+			klog.Infof("delete failed MinIO Pod %s/%s", pod.Namespace, pod.Name)
+			if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ServerPodRecreated", "MinIO Pod %s failed (%s), deleted to be recreated", name, pod.Status.Reason)
+			continue
+		}
+
+		// 所在节点已被移除时 Pod 会一直处于 Terminating 状态，需要强制删除后才能重建
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		var node corev1.Node
+		err := r.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, &node)
+		if err == nil {
+			continue
+		}
+		if !errors.IsNotFound(err) {
 			return err
 		}
-		klog.V(2).Info("Creating new PVC")
-		var allPvcs []*corev1.PersistentVolumeClaim
-		for _, pool := range minio.Spec.Pools {
+		klog.Infof("force delete MinIO Pod %s/%s on removed node %s", pod.Namespace, pod.Name, pod.Spec.NodeName)
+		if err := r.Delete(ctx, pod, client.GracePeriodSeconds(0), client.Preconditions{UID: &pod.UID}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ServerPodRecreated", "MinIO Pod %s on removed node %s force deleted to be recreated", name, pod.Spec.NodeName)
+	}
+
+	return nil
+}
 
-			pvcs := utils.NewPersistentVolumeClaimForMinIOPool(ctx, minio, &pool)
-			allPvcs = append(allPvcs, pvcs...)
+// 校验已部署的服务池的卷数量是否被修改，返回拒绝的原因
+// MinIO 无法改变已有服务池的盘数量，StatefulSet 的 volumeClaimTemplates 也不可修改
+func (r *MinIOReconciler) checkPoolVolumes(ctx context.Context, minio *miniov1alpha1.MinIO) (string, error) {
+	for i := range minio.Spec.Pools {
+		pool := &minio.Spec.Pools[i]
+		ss, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Get(ctx, minio.PoolStatefulSetName(pool), metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		if deployed := len(ss.Spec.VolumeClaimTemplates); deployed != pool.VolumesPerServer {
+			msg := fmt.Sprintf("volumesPerServer of pool %s can not be changed from %d to %d", pool.Name, deployed, pool.VolumesPerServer)
+			klog.Errorf("MinIO %s/%s: %s", minio.Namespace, minio.Name, msg)
+			return msg, nil
 		}
+	}
+	return "", nil
+}
 
-		for _, pvc := range allPvcs {
-			if err := r.Create(ctx, pvc); err != nil {
-				klog.Errorf("Create PVC %s/%s error: %s", pvc.Namespace, pvc.Name, err)
-				r.Recorder.Event(minio, corev1.EventTypeWarning, "CreatePVCFailed", "Create PVC Failed")
+// 按服务池及 (server, volume) 索引校验 PVC，创建缺失的 PVC
+func (r *MinIOReconciler) checkPVC(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) error {
+	for _, pvc := range utils.NewPersistentVolumeClaimForMinIOPool(ctx, minio, pool) {
+		var found corev1.PersistentVolumeClaim
+		err := r.Get(ctx, client.ObjectKeyFromObject(pvc), &found)
+		if err == nil {
+			changed, err := r.expandPVC(ctx, minio, pvc, &found)
+			if err != nil {
+				return err
+			}
+			if utils.SyncPersistentVolumeClaimOwner(minio, &found) {
+				changed = true
+			}
+			// 复用删除 MinIO 实例或下线服务池时保留下来的 PVC
+			_, orphaned := found.Labels[miniov1alpha1.OrphanedLabel]
+			if orphaned {
+				delete(found.Labels, miniov1alpha1.OrphanedLabel)
+				for k, v := range minio.MinIOPoolLabels(pool) {
+					found.Labels[k] = v
+				}
+				changed = true
+			}
+			if !changed {
+				continue
+			}
+			if err := r.Update(ctx, &found); err != nil {
+				return err
+			}
+			if orphaned {
+				r.Recorder.Eventf(minio, corev1.EventTypeNormal, "PVCReused", "Orphaned PVC %s reused", found.Name)
 			}
+			continue
+		}
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		klog.V(2).Infof("Creating new PVC %s/%s", pvc.Namespace, pvc.Name)
+		if err := r.Create(ctx, pvc); err != nil && !errors.IsAlreadyExists(err) {
+			klog.Errorf("Create PVC %s/%s error: %s", pvc.Namespace, pvc.Name, err)
+			r.Recorder.Eventf(minio, corev1.EventTypeWarning, "CreatePVCFailed", "Create PVC %s Failed, %s", pvc.Name, err)
+			return err
 		}
 	}
+
 	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MinIOReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.statusWriter = newStatusWriter(mgr.GetClient(), minioFieldManager, minioStatusFields)
 	return ctrl.NewControllerManagedBy(mgr).
+		Named("minio").
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		For(&miniov1alpha1.MinIO{}).
-		For(&corev1.Pod{}).
-		For(&corev1.Service{}).
-		// For(&corev1.PersistentVolumeClaim{}).
-		// For(&corev1.Secret{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(minioForObject)).
+		Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, handler.EnqueueRequestsFromMapFunc(minioForObject)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.minioForSecret)).
 		Complete(r)
 }
+
+// 根据 Pod、PVC 等资源的 MinIOLable 找到其所属的 MinIO 实例
+func minioForObject(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[miniov1alpha1.MinIOLable]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}},
+	}
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+)
+
+var _ = Describe("MinIO controllers", func() {
+	const (
+		timeout  = 30 * time.Second
+		interval = 250 * time.Millisecond
+	)
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "minio-sample", Namespace: "default"}
+
+	It("deploys, reports status and health checks a MinIO instance", func() {
+		minio := &miniov1alpha1.MinIO{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: miniov1alpha1.MinIOSpec{
+				Image: "minio/minio:latest",
+				Pools: []miniov1alpha1.Pool{
+					{
+						Name:             "pool-0",
+						Servers:          4,
+						VolumesPerServer: 1,
+						VolumeClaimTemplate: &corev1.PersistentVolumeClaim{
+							Spec: corev1.PersistentVolumeClaimSpec{
+								AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{
+										corev1.ResourceStorage: resource.MustParse("1Gi"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, minio)).To(Succeed())
+
+		By("MinIOReconciler adds the finalizer and creates the StatefulSet")
+		Eventually(func() bool {
+			var found miniov1alpha1.MinIO
+			if err := k8sClient.Get(ctx, key, &found); err != nil {
+				return false
+			}
+			return controllerutil.ContainsFinalizer(&found, miniov1alpha1.MinIOFinalizer)
+		}, timeout, interval).Should(BeTrue())
+		Eventually(func() error {
+			var ss appsv1.StatefulSet
+			return k8sClient.Get(ctx, types.NamespacedName{Name: minio.PoolStatefulSetName(&minio.Spec.Pools[0]), Namespace: key.Namespace}, &ss)
+		}, timeout, interval).Should(Succeed())
+
+		By("MinIOStatusReconciler reports the service addresses and pool status")
+		Eventually(func() bool {
+			var found miniov1alpha1.MinIO
+			if err := k8sClient.Get(ctx, key, &found); err != nil {
+				return false
+			}
+			return found.Status.Service != nil && found.Status.Service.MinIO != "" && len(found.Status.PoolStatus) == 1
+		}, timeout, interval).Should(BeTrue())
+
+		By("MinIOHealthCheckerReconciler reports the health status")
+		Eventually(func() bool {
+			var found miniov1alpha1.MinIO
+			if err := k8sClient.Get(ctx, key, &found); err != nil {
+				return false
+			}
+			return meta.FindStatusCondition(found.Status.Conditions, miniov1alpha1.ConditionHealthy) != nil
+		}, timeout, interval).Should(BeTrue())
+
+		By("each controller keeps its own conditions")
+		Eventually(func() []string {
+			var found miniov1alpha1.MinIO
+			if err := k8sClient.Get(ctx, key, &found); err != nil {
+				return nil
+			}
+			var types []string
+			for _, cond := range found.Status.Conditions {
+				types = append(types, cond.Type)
+			}
+			return types
+		}, timeout, interval).Should(ContainElements(
+			miniov1alpha1.ConditionProgressing,
+			miniov1alpha1.ConditionAvailable,
+			miniov1alpha1.ConditionHealthy,
+		))
+	})
+})
+
+func TestReconcileRejectsInvalidErasureSets(t *testing.T) {
+	minio := newTestMinIO(17, 2)
+	minio.Finalizers = []string{miniov1alpha1.MinIOFinalizer}
+	r := newTestReconciler(t, []client.Object{minio})
+	c := &patchCountingClient{Client: r.Client}
+	r.statusWriter = newStatusWriter(c, minioFieldManager, minioStatusFields)
+
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(minio)}); err != nil {
+		t.Fatal(err)
+	}
+	if c.applied == nil {
+		t.Fatalf("status was not applied")
+	}
+	degraded := meta.FindStatusCondition(c.applied.Status.Conditions, miniov1alpha1.ConditionDegraded)
+	if degraded == nil || degraded.Status != metav1.ConditionTrue || degraded.Reason != "InvalidErasureCoding" {
+		t.Errorf("Degraded condition = %+v, want True with reason InvalidErasureCoding", degraded)
+	}
+	var ssList appsv1.StatefulSetList
+	if err := r.List(context.TODO(), &ssList); err != nil {
+		t.Fatal(err)
+	}
+	if len(ssList.Items) != 0 {
+		t.Errorf("%d StatefulSets created for an invalid pool", len(ssList.Items))
+	}
+}
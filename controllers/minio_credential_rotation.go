@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// 所有 Pod 使用新凭证重启并就绪后，验证管理接口的最长等待时间，超时后回滚
+	credentialRotationTimeout = 5 * time.Minute
+
+	// 等待凭证验证的重试间隔
+	credentialRotationRequeueInterval = 10 * time.Second
+)
+
+// 返回 true 表示正在轮换或回滚 root 凭证，此时所有 Pod 需要同时重启
+func credentialRotationInProgress(minio *miniov1alpha1.MinIO) bool {
+	status := minio.Status.CredentialRotation
+	return status != nil && (status.Phase == miniov1alpha1.CredentialRotationRotating || status.Phase == miniov1alpha1.CredentialRotationRollingBack)
+}
+
+// rotate-credentials 注解的值变化时生成新的 root 凭证并写入配置 Secret，原有的 config.env 保存在 PreviousConfigurationKey 中
+// 已处理的请求记录在配置 Secret 的同名注解上，缓存过期或状态写入失败后重新调谐不会再次生成凭证
+func (r *MinIOReconciler) startCredentialRotation(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	request := minio.Annotations[miniov1alpha1.RotateCredentialsAnnotation]
+	if request == "" || credentialRotationInProgress(minio) {
+		return nil
+	}
+	status := minio.Status.CredentialRotation
+	if status != nil && status.ObservedRequest == request {
+		return nil
+	}
+
+	secret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.ConfigurationSecretName(), metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("get configuration Secret %s/%s error, %s", minio.Namespace, minio.ConfigurationSecretName(), err)
+		return err
+	}
+	if secret.Annotations[miniov1alpha1.RotateCredentialsAnnotation] != request {
+		configuration, err := utils.RotateRawConfiguration(secret.Data["config.env"])
+		if err != nil {
+			klog.Errorf("generate credentials for MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+			return err
+		}
+		// 上一次轮换未结束时保留最初的 config.env，避免覆盖为未经验证的凭证
+		if _, ok := secret.Data[miniov1alpha1.PreviousConfigurationKey]; !ok {
+			secret.Data[miniov1alpha1.PreviousConfigurationKey] = secret.Data["config.env"]
+		}
+		secret.Data["config.env"] = configuration
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[miniov1alpha1.RotateCredentialsAnnotation] = request
+		if _, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("update configuration Secret %s/%s error, %s", secret.Namespace, secret.Name, err)
+			return err
+		}
+	}
+
+	now := metav1.Now()
+	newStatus := &miniov1alpha1.CredentialRotationStatus{
+		Phase:           miniov1alpha1.CredentialRotationRotating,
+		ObservedRequest: request,
+		StartTime:       &now,
+	}
+	if status != nil {
+		newStatus.LastRotationTime = status.LastRotationTime
+	}
+	minio.Status.CredentialRotation = newStatus
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "CredentialRotationStarted", "Root credentials in Secret %s rotated, restarting MinIO servers", secret.Name)
+
+	// 立即记录状态，避免重复轮换
+	return r.statusWriter.Apply(ctx, minio)
+}
+
+// 所有 Pod 使用新凭证重启后通过管理接口验证凭证，返回 true 表示需要继续等待
+// 验证超时则恢复原有凭证并再次重启，回滚完成后将轮换标记为失败
+func (r *MinIOReconciler) verifyCredentialRotation(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+	if !credentialRotationInProgress(minio) {
+		return false, nil
+	}
+	status := minio.Status.CredentialRotation
+
+	err := r.verifyAdminAccess(ctx, minio)
+	if err == nil {
+		if err := r.removePreviousConfiguration(ctx, minio); err != nil {
+			return true, err
+		}
+		if status.Phase == miniov1alpha1.CredentialRotationRotating {
+			now := metav1.Now()
+			status.Phase = miniov1alpha1.CredentialRotationSucceeded
+			status.LastRotationTime = &now
+			status.Message = ""
+			r.Recorder.Event(minio, corev1.EventTypeNormal, "CredentialRotationSucceeded", "Root credentials rotated and verified")
+		} else {
+			status.Phase = miniov1alpha1.CredentialRotationFailed
+			r.Recorder.Event(minio, corev1.EventTypeWarning, "CredentialRotationRolledBack", "Root credentials rolled back to the previous ones")
+		}
+		return false, nil
+	}
+
+	status.Message = err.Error()
+	if status.Phase == miniov1alpha1.CredentialRotationRollingBack {
+		return true, nil
+	}
+	// 滚动重启的耗时不计入验证时间，从首次验证开始计算超时
+	if status.VerifyStartTime == nil {
+		now := metav1.Now()
+		status.VerifyStartTime = &now
+	}
+	if time.Since(status.VerifyStartTime.Time) < credentialRotationTimeout {
+		return true, nil
+	}
+
+	// 新凭证验证超时，恢复原有凭证
+	klog.Errorf("verify rotated credentials of MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+	secret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.ConfigurationSecretName(), metav1.GetOptions{})
+	if err != nil {
+		return true, err
+	}
+	previous, ok := secret.Data[miniov1alpha1.PreviousConfigurationKey]
+	if !ok {
+		status.Phase = miniov1alpha1.CredentialRotationFailed
+		status.Message = "rotated credentials can not be verified and previous configuration is missing"
+		r.Recorder.Event(minio, corev1.EventTypeWarning, "CredentialRotationFailed", status.Message)
+		return false, nil
+	}
+	secret.Data["config.env"] = previous
+	if _, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("update configuration Secret %s/%s error, %s", secret.Namespace, secret.Name, err)
+		return true, err
+	}
+	status.Phase = miniov1alpha1.CredentialRotationRollingBack
+	r.Recorder.Eventf(minio, corev1.EventTypeWarning, "CredentialRotationFailed", "Rotated root credentials can not be verified, rolling back, %s", status.Message)
+
+	return true, nil
+}
+
+// 使用配置 Secret 中的 root 凭证调用管理接口
+func (r *MinIOReconciler) verifyAdminAccess(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	credentials, err := getMinIOCredentials(ctx, r.KubeClient, minio)
+	if err != nil {
+		return err
+	}
+	tr, err := newMinIOTransport(ctx, r.KubeClient, minio)
+	if err != nil {
+		return err
+	}
+	adminClnt, err := minio.NewMinIOAdminForAddress(r.adminAddress, credentials, tr)
+	if err != nil {
+		return err
+	}
+	_, err = adminClnt.ServerInfo(ctx)
+	return err
+}
+
+// 轮换结束后删除配置 Secret 中保存的原有 config.env
+func (r *MinIOReconciler) removePreviousConfiguration(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	secret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.ConfigurationSecretName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, ok := secret.Data[miniov1alpha1.PreviousConfigurationKey]; !ok {
+		return nil
+	}
+	delete(secret.Data, miniov1alpha1.PreviousConfigurationKey)
+	_, err = r.KubeClient.CoreV1().Secrets(minio.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
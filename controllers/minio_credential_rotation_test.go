@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVerifyCredentialRotation(t *testing.T) {
+	ago := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(time.Now().Add(-d))
+		return &t
+	}
+	tests := []struct {
+		name            string
+		phase           miniov1alpha1.CredentialRotationPhase
+		verifyStartTime *metav1.Time
+		accessible      bool
+		wantWaiting     bool
+		wantPhase       miniov1alpha1.CredentialRotationPhase
+		wantRestored    bool
+		wantEvent       string
+	}{
+		{
+			name:       "verified",
+			phase:      miniov1alpha1.CredentialRotationRotating,
+			accessible: true,
+			wantPhase:  miniov1alpha1.CredentialRotationSucceeded,
+			wantEvent:  "CredentialRotationSucceeded",
+		},
+		{
+			// 滚动重启耗时超过超时时间，开始验证后仍有完整的等待时间
+			name:        "first verification after a long restart",
+			phase:       miniov1alpha1.CredentialRotationRotating,
+			wantWaiting: true,
+			wantPhase:   miniov1alpha1.CredentialRotationRotating,
+		},
+		{
+			name:            "verification within the timeout",
+			phase:           miniov1alpha1.CredentialRotationRotating,
+			verifyStartTime: ago(time.Minute),
+			wantWaiting:     true,
+			wantPhase:       miniov1alpha1.CredentialRotationRotating,
+		},
+		{
+			name:            "verification timed out",
+			phase:           miniov1alpha1.CredentialRotationRotating,
+			verifyStartTime: ago(credentialRotationTimeout + time.Minute),
+			wantWaiting:     true,
+			wantPhase:       miniov1alpha1.CredentialRotationRollingBack,
+			wantRestored:    true,
+			wantEvent:       "CredentialRotationFailed",
+		},
+		{
+			name:            "rolling back never times out",
+			phase:           miniov1alpha1.CredentialRotationRollingBack,
+			verifyStartTime: ago(credentialRotationTimeout + time.Minute),
+			wantWaiting:     true,
+			wantPhase:       miniov1alpha1.CredentialRotationRollingBack,
+		},
+		{
+			name:       "rolled back",
+			phase:      miniov1alpha1.CredentialRotationRollingBack,
+			accessible: true,
+			wantPhase:  miniov1alpha1.CredentialRotationFailed,
+			wantEvent:  "CredentialRotationRolledBack",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if !tt.accessible {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				_, _ = w.Write([]byte("{}"))
+			}))
+			defer server.Close()
+
+			minio := newTestMinIO(4, 1)
+			minio.Status.CredentialRotation = &miniov1alpha1.CredentialRotationStatus{
+				Phase:           tt.phase,
+				StartTime:       ago(time.Hour),
+				VerifyStartTime: tt.verifyStartTime,
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: minio.ConfigurationSecretName(), Namespace: minio.Namespace},
+				Data: map[string][]byte{
+					"config.env":                           []byte("export MINIO_ROOT_USER=rotated\nexport MINIO_ROOT_PASSWORD=rotated123\n"),
+					miniov1alpha1.PreviousConfigurationKey: []byte("export MINIO_ROOT_USER=minio\nexport MINIO_ROOT_PASSWORD=minio123\n"),
+				},
+			}
+			r := newTestReconciler(t, nil, secret)
+			r.adminAddress = strings.TrimPrefix(server.URL, "http://")
+
+			waiting, err := r.verifyCredentialRotation(context.TODO(), minio)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if waiting != tt.wantWaiting {
+				t.Errorf("waiting = %v, want %v", waiting, tt.wantWaiting)
+			}
+			status := minio.Status.CredentialRotation
+			if status.Phase != tt.wantPhase {
+				t.Errorf("phase = %s, want %s", status.Phase, tt.wantPhase)
+			}
+			if !tt.accessible && status.VerifyStartTime == nil {
+				t.Errorf("verifyStartTime not recorded")
+			}
+
+			found, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored := string(found.Data["config.env"]) == string(secret.Data[miniov1alpha1.PreviousConfigurationKey])
+			if restored != tt.wantRestored {
+				t.Errorf("previous config.env restored = %v, want %v", restored, tt.wantRestored)
+			}
+			if _, ok := found.Data[miniov1alpha1.PreviousConfigurationKey]; ok == tt.accessible {
+				t.Errorf("previous config.env kept = %v, want %v", ok, !tt.accessible)
+			}
+			events := recordedEvents(r)
+			if tt.wantEvent == "" && len(events) != 0 {
+				t.Errorf("events = %v, want none", events)
+			}
+			if tt.wantEvent != "" && (len(events) != 1 || !strings.Contains(events[0], tt.wantEvent)) {
+				t.Errorf("events = %v, want %s", events, tt.wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	stderr "errors"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var ErrMalformedRootCredentials = stderr.New("malformed tenant credentials, root user must be at least 3 characters and password at least 8 characters")
+
+// MinIO 对 root 用户名和密码的最小长度要求
+const (
+	minRootUserLength     = 3
+	minRootPasswordLength = 8
+)
+
+// 从配置 Secret 的 config.env 中解析 root 用户名和密码，用于调用 MinIO 管理接口
+func getMinIOCredentials(ctx context.Context, kubeClient kubernetes.Interface, minio *miniov1alpha1.MinIO) (map[string][]byte, error) {
+	secret, err := kubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.ConfigurationSecretName(), metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("get configuration Secret %s/%s error, %s", minio.Namespace, minio.ConfigurationSecretName(), err)
+		return nil, err
+	}
+
+	minioConfiguration := utils.ParseRawConfiguration(secret.Data["config.env"])
+	accessKey, secretKey := minioConfiguration["accesskey"], minioConfiguration["secretkey"]
+	if len(accessKey) == 0 || len(secretKey) == 0 {
+		return minioConfiguration, ErrEmptyRootCredentials
+	}
+	if len(accessKey) < minRootUserLength || len(secretKey) < minRootPasswordLength {
+		return minioConfiguration, ErrMalformedRootCredentials
+	}
+
+	return minioConfiguration, nil
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 等待服务池数据迁移的重试间隔
+const decommissionRequeueInterval = 30 * time.Second
+
+// 返回实际部署的服务池，按以下规则在 Spec.Pools 的基础上调整:
+// 已从 Spec.Pools 中移除但 StatefulSet 仍存在的服务池需要先下线，按原位置保留并标记为 Decommission；
+// 标记为 Decommission 且 StatefulSet 已删除的服务池已完成下线，不再部署
+func effectivePools(ctx context.Context, c client.Client, kubeClient kubernetes.Interface, minio *miniov1alpha1.MinIO) ([]miniov1alpha1.Pool, error) {
+	poolExists := func(pool *miniov1alpha1.Pool) (bool, error) {
+		_, err := kubeClient.AppsV1().StatefulSets(minio.Namespace).Get(ctx, minio.PoolStatefulSetName(pool), metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	var pools []miniov1alpha1.Pool
+	names := make(map[string]bool, len(minio.Spec.Pools))
+	for i := range minio.Spec.Pools {
+		pool := minio.Spec.Pools[i]
+		names[pool.Name] = true
+		if pool.Decommission {
+			exists, err := poolExists(&pool)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				continue
+			}
+		}
+		pools = append(pools, pool)
+	}
+
+	// 已完成部署的规格中存在、当前规格中被移除的服务池
+	current, err := revisionSpec(ctx, c, minio, minio.Status.CurrentRevision)
+	if err != nil || current == nil {
+		return pools, err
+	}
+	for i := range current.Pools {
+		pool := current.Pools[i]
+		if names[pool.Name] {
+			continue
+		}
+		exists, err := poolExists(&pool)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		pool.Decommission = true
+		index := i
+		if index > len(pools) {
+			index = len(pools)
+		}
+		pools = append(pools[:index], append([]miniov1alpha1.Pool{pool}, pools[index:]...)...)
+	}
+
+	return pools, nil
+}
+
+// 下线服务池，返回 true 表示数据已迁移完成且服务池的 StatefulSet 已删除
+// 由 MinIO 将服务池中的数据迁移到其他服务池，完成后删除 StatefulSet，并按 ReclaimStorage 删除或保留 PVC
+func (r *MinIOReconciler) decommissionPool(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) (bool, error) {
+	status := &miniov1alpha1.PoolDecommissionStatus{Status: miniov1alpha1.DecommissionPending}
+	defer setPoolDecommissionStatus(minio, pool.Name, status)
+
+	credentials, err := getMinIOCredentials(ctx, r.KubeClient, minio)
+	if err != nil {
+		status.Message = err.Error()
+		return false, nil
+	}
+	tr, err := newMinIOTransport(ctx, r.KubeClient, minio)
+	if err != nil {
+		status.Message = err.Error()
+		return false, nil
+	}
+	adminClnt, err := minio.NewMinIOAdminForAddress(r.adminAddress, credentials, tr)
+	if err != nil {
+		status.Message = err.Error()
+		return false, nil
+	}
+
+	endpoint := minio.PoolEndpoint(pool)
+	poolStatus, err := adminClnt.StatusPool(ctx, endpoint)
+	if err != nil {
+		klog.Errorf("query decommission status of pool %s error, %s", pool.Name, err)
+		status.Message = err.Error()
+		return false, nil
+	}
+
+	info := poolStatus.Decommission
+	if info == nil || info.StartTime.IsZero() {
+		if err := adminClnt.DecommissionPool(ctx, endpoint); err != nil {
+			klog.Errorf("start decommission of pool %s error, %s", pool.Name, err)
+			status.Message = err.Error()
+			return false, nil
+		}
+		status.Status = miniov1alpha1.DecommissionDraining
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "DecommissionStarted", "Decommission of pool %s started", pool.Name)
+		return false, nil
+	}
+
+	startTime := metav1.NewTime(info.StartTime)
+	status.StartTime = &startTime
+	status.StartSize = info.StartSize
+	status.TotalSize = info.TotalSize
+	status.CurrentSize = info.CurrentSize
+	switch {
+	case info.Failed:
+		status.Status = miniov1alpha1.DecommissionFailed
+		status.Message = "decommission failed, check MinIO server logs and restart it with mc admin decommission start"
+		r.Recorder.Eventf(minio, corev1.EventTypeWarning, "DecommissionFailed", "Decommission of pool %s failed", pool.Name)
+		return false, nil
+	case info.Canceled:
+		status.Status = miniov1alpha1.DecommissionCanceled
+		status.Message = "decommission canceled, remove decommission flag or restart it with mc admin decommission start"
+		return false, nil
+	case !info.Complete:
+		status.Status = miniov1alpha1.DecommissionDraining
+		return false, nil
+	}
+
+	// 数据迁移完成，删除服务池的 StatefulSet 及 Pod
+	status.Status = miniov1alpha1.DecommissionComplete
+	background := metav1.DeletePropagationBackground
+	if err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Delete(ctx, minio.PoolStatefulSetName(pool), metav1.DeleteOptions{PropagationPolicy: &background}); err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("delete StatefulSet of pool %s error, %s", pool.Name, err)
+		return false, err
+	}
+	if err := r.reclaimPVCs(ctx, minio, minio.MinIOPoolLabels(pool)); err != nil {
+		return false, err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "DecommissionCompleted", "Pool %s decommissioned", pool.Name)
+
+	return true, nil
+}
+
+// 记录服务池的下线进度
+func setPoolDecommissionStatus(minio *miniov1alpha1.MinIO, name string, status *miniov1alpha1.PoolDecommissionStatus) {
+	for i := range minio.Status.PoolStatus {
+		if minio.Status.PoolStatus[i].Name == name {
+			minio.Status.PoolStatus[i].Decommission = status
+			return
+		}
+	}
+	minio.Status.PoolStatus = append(minio.Status.PoolStatus, miniov1alpha1.PoolStatus{
+		Name:         name,
+		Decommission: status,
+	})
+}
+
+// 清除已完成下线或取消下线标记的服务池的下线进度，服务池的 StatefulSet 删除后不再需要展示
+func clearPoolDecommissionStatus(minio *miniov1alpha1.MinIO) {
+	decommissioning := make(map[string]bool)
+	for _, pool := range minio.Spec.Pools {
+		decommissioning[pool.Name] = pool.Decommission
+	}
+	for i := range minio.Status.PoolStatus {
+		if !decommissioning[minio.Status.PoolStatus[i].Name] {
+			minio.Status.PoolStatus[i].Decommission = nil
+		}
+	}
+}
+
+// 返回 MinIO 实例的所有 StatefulSet，包括已从 Spec.Pools 中移除的服务池
+func (r *MinIOReconciler) listStatefulSets(ctx context.Context, minio *miniov1alpha1.MinIO) ([]appsv1.StatefulSet, error) {
+	var ssList appsv1.StatefulSetList
+	if err := r.List(ctx, &ssList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPodLabels())); err != nil {
+		klog.Errorf("query StatefulSet list error, %s", err)
+		return nil, err
+	}
+	var items []appsv1.StatefulSet
+	for _, ss := range ssList.Items {
+		if metav1.IsControlledBy(&ss, minio) {
+			items = append(items, ss)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	"github.com/minio/madmin-go/v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestStatefulSet(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: minio.PoolStatefulSetName(pool), Namespace: minio.Namespace, Labels: minio.MinIOPoolLabels(pool)},
+	}
+}
+
+// 将 pools 记录为已完成部署的规格
+func deployedRevision(minio *miniov1alpha1.MinIO, pools ...miniov1alpha1.Pool) *appsv1.ControllerRevision {
+	deployed := minio.DeepCopy()
+	deployed.Spec.Pools = pools
+	cr := deployed.NewControllerRevision(1)
+	cr.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(minio, miniov1alpha1.GroupVersion.WithKind(miniov1alpha1.MinIOCRDResourceKind))}
+	minio.Status.CurrentRevision = cr.Name
+	return cr
+}
+
+func TestEffectivePools(t *testing.T) {
+	pool := func(name string, decommission bool) miniov1alpha1.Pool {
+		return miniov1alpha1.Pool{Name: name, Servers: 4, VolumesPerServer: 1, Decommission: decommission}
+	}
+	tests := []struct {
+		name        string
+		pools       []miniov1alpha1.Pool
+		deployed    []miniov1alpha1.Pool
+		deployedSts []string
+		want        []miniov1alpha1.Pool
+	}{
+		{
+			name:  "pools without revision",
+			pools: []miniov1alpha1.Pool{pool("pool-0", false), pool("pool-1", false)},
+			want:  []miniov1alpha1.Pool{pool("pool-0", false), pool("pool-1", false)},
+		},
+		{
+			name:        "decommissioning pool is kept",
+			pools:       []miniov1alpha1.Pool{pool("pool-0", true), pool("pool-1", false)},
+			deployedSts: []string{"pool-0", "pool-1"},
+			want:        []miniov1alpha1.Pool{pool("pool-0", true), pool("pool-1", false)},
+		},
+		{
+			name:        "decommissioned pool is dropped",
+			pools:       []miniov1alpha1.Pool{pool("pool-0", true), pool("pool-1", false)},
+			deployedSts: []string{"pool-1"},
+			want:        []miniov1alpha1.Pool{pool("pool-1", false)},
+		},
+		{
+			name:        "removed pool is decommissioned at its position",
+			pools:       []miniov1alpha1.Pool{pool("pool-1", false), pool("pool-2", false)},
+			deployed:    []miniov1alpha1.Pool{pool("pool-0", false), pool("pool-1", false)},
+			deployedSts: []string{"pool-0", "pool-1"},
+			want:        []miniov1alpha1.Pool{pool("pool-0", true), pool("pool-1", false), pool("pool-2", false)},
+		},
+		{
+			name:        "removed pool without StatefulSet is dropped",
+			pools:       []miniov1alpha1.Pool{pool("pool-1", false)},
+			deployed:    []miniov1alpha1.Pool{pool("pool-0", false), pool("pool-1", false)},
+			deployedSts: []string{"pool-1"},
+			want:        []miniov1alpha1.Pool{pool("pool-1", false)},
+		},
+		{
+			name:        "removed last pool is appended",
+			pools:       []miniov1alpha1.Pool{pool("pool-0", false)},
+			deployed:    []miniov1alpha1.Pool{pool("pool-0", false), pool("pool-1", false), pool("pool-2", false)},
+			deployedSts: []string{"pool-0", "pool-1", "pool-2"},
+			want:        []miniov1alpha1.Pool{pool("pool-0", false), pool("pool-1", true), pool("pool-2", true)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO(4, 1)
+			minio.Spec.Pools = tt.pools
+			var objs []client.Object
+			if tt.deployed != nil {
+				objs = append(objs, deployedRevision(minio, tt.deployed...))
+			}
+			var kubeObjs []runtime.Object
+			for _, name := range tt.deployedSts {
+				kubeObjs = append(kubeObjs, newTestStatefulSet(minio, &miniov1alpha1.Pool{Name: name}))
+			}
+
+			r := newTestReconciler(t, objs, kubeObjs...)
+			got, err := effectivePools(context.TODO(), r.Client, r.KubeClient, minio)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("pools = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Name != tt.want[i].Name || got[i].Decommission != tt.want[i].Decommission {
+					t.Errorf("pools[%d] = %s (decommission %v), want %s (decommission %v)", i, got[i].Name, got[i].Decommission, tt.want[i].Name, tt.want[i].Decommission)
+				}
+			}
+		})
+	}
+}
+
+// 模拟 MinIO 的服务池下线接口，返回 info 作为下线进度
+func newDecommissionServer(t *testing.T, info *madmin.PoolDecommissionInfo, started *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/pools/status"):
+			if err := json.NewEncoder(w).Encode(madmin.PoolStatus{Decommission: info}); err != nil {
+				t.Error(err)
+			}
+		case strings.HasSuffix(req.URL.Path, "/pools/decommission"):
+			*started++
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDecommissionPool(t *testing.T) {
+	startTime := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name           string
+		info           *madmin.PoolDecommissionInfo
+		reclaimStorage bool
+		wantDone       bool
+		wantStarted    int
+		wantStatus     miniov1alpha1.DecommissionStatus
+		wantEvent      string
+	}{
+		{name: "not started", wantStarted: 1, wantStatus: miniov1alpha1.DecommissionDraining, wantEvent: "DecommissionStarted"},
+		{name: "draining", info: &madmin.PoolDecommissionInfo{StartTime: startTime, TotalSize: 100, CurrentSize: 50}, wantStatus: miniov1alpha1.DecommissionDraining},
+		{name: "failed", info: &madmin.PoolDecommissionInfo{StartTime: startTime, Failed: true}, wantStatus: miniov1alpha1.DecommissionFailed, wantEvent: "DecommissionFailed"},
+		{name: "canceled", info: &madmin.PoolDecommissionInfo{StartTime: startTime, Canceled: true}, wantStatus: miniov1alpha1.DecommissionCanceled},
+		{name: "complete retains PVCs", info: &madmin.PoolDecommissionInfo{StartTime: startTime, Complete: true}, wantDone: true, wantStatus: miniov1alpha1.DecommissionComplete, wantEvent: "DecommissionCompleted"},
+		{name: "complete deletes PVCs", info: &madmin.PoolDecommissionInfo{StartTime: startTime, Complete: true}, reclaimStorage: true, wantDone: true, wantStatus: miniov1alpha1.DecommissionComplete, wantEvent: "DecommissionCompleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := 0
+			server := newDecommissionServer(t, tt.info, &started)
+			defer server.Close()
+
+			minio := newTestMinIO(4, 1)
+			minio.Spec.ReclaimStorage = tt.reclaimStorage
+			pool := &minio.Spec.Pools[0]
+			pool.Decommission = true
+			pvc := newTestPVC(minio, pool, 0, nil, "10Gi")
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: minio.ConfigurationSecretName(), Namespace: minio.Namespace},
+				Data:       map[string][]byte{"config.env": []byte("export MINIO_ROOT_USER=minio\nexport MINIO_ROOT_PASSWORD=minio123\n")},
+			}
+
+			r := newTestReconciler(t, []client.Object{pvc}, secret, newTestStatefulSet(minio, pool))
+			r.adminAddress = strings.TrimPrefix(server.URL, "http://")
+			done, err := r.decommissionPool(context.TODO(), minio, pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != tt.wantDone {
+				t.Errorf("done = %v, want %v", done, tt.wantDone)
+			}
+			if started != tt.wantStarted {
+				t.Errorf("decommission started %d times, want %d", started, tt.wantStarted)
+			}
+			if len(minio.Status.PoolStatus) != 1 || minio.Status.PoolStatus[0].Decommission == nil {
+				t.Fatalf("pool status = %+v, want decommission status", minio.Status.PoolStatus)
+			}
+			if got := minio.Status.PoolStatus[0].Decommission; got.Status != tt.wantStatus {
+				t.Errorf("decommission status = %s (%s), want %s", got.Status, got.Message, tt.wantStatus)
+			}
+			events := recordedEvents(r)
+			if tt.wantEvent == "" && len(events) != 0 {
+				t.Errorf("events = %v, want none", events)
+			}
+			if tt.wantEvent != "" && (len(events) == 0 || !strings.Contains(events[len(events)-1], tt.wantEvent)) {
+				t.Errorf("events = %v, want %s", events, tt.wantEvent)
+			}
+
+			_, err = r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Get(context.TODO(), minio.PoolStatefulSetName(pool), metav1.GetOptions{})
+			if deleted := errors.IsNotFound(err); deleted != tt.wantDone {
+				t.Errorf("StatefulSet deleted = %v, want %v", deleted, tt.wantDone)
+			}
+			var found corev1.PersistentVolumeClaim
+			err = r.Get(context.TODO(), client.ObjectKeyFromObject(pvc), &found)
+			switch {
+			case !tt.wantDone:
+				if err != nil || found.Labels[miniov1alpha1.PoolLabel] != pool.Name {
+					t.Errorf("PVC labels = %v, %v, want unchanged", found.Labels, err)
+				}
+			case tt.reclaimStorage:
+				if !errors.IsNotFound(err) {
+					t.Errorf("PVC was not deleted, %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := found.Labels[miniov1alpha1.MinIOLable]; ok || found.Labels[miniov1alpha1.OrphanedLabel] != "true" {
+					t.Errorf("PVC labels = %v, want orphaned without MinIO labels", found.Labels)
+				}
+			}
+		})
+	}
+
+	t.Run("MinIO unreachable", func(t *testing.T) {
+		minio := newTestMinIO(4, 1)
+		pool := &minio.Spec.Pools[0]
+		r := newTestReconciler(t, nil)
+		done, err := r.decommissionPool(context.TODO(), minio, pool)
+		if err != nil || done {
+			t.Fatalf("decommissionPool = %v, %v, want false, nil", done, err)
+		}
+		if got := minio.Status.PoolStatus[0].Decommission; got.Status != miniov1alpha1.DecommissionPending || got.Message == "" {
+			t.Errorf("decommission status = %+v, want Pending with a message", got)
+		}
+	})
+}
+
+func TestClearPoolDecommissionStatus(t *testing.T) {
+	minio := newTestMinIO(4, 1)
+	minio.Spec.Pools = []miniov1alpha1.Pool{
+		{Name: "pool-0", Decommission: true},
+		{Name: "pool-1"},
+	}
+	complete := &miniov1alpha1.PoolDecommissionStatus{Status: miniov1alpha1.DecommissionComplete}
+	minio.Status.PoolStatus = []miniov1alpha1.PoolStatus{
+		{Name: "pool-0", Decommission: &miniov1alpha1.PoolDecommissionStatus{Status: miniov1alpha1.DecommissionDraining}},
+		{Name: "pool-1", Decommission: &miniov1alpha1.PoolDecommissionStatus{Status: miniov1alpha1.DecommissionCanceled}},
+		{Name: "pool-2", Decommission: complete},
+	}
+
+	clearPoolDecommissionStatus(minio)
+	if minio.Status.PoolStatus[0].Decommission == nil {
+		t.Errorf("decommission status of decommissioning pool-0 was cleared")
+	}
+	for _, ps := range minio.Status.PoolStatus[1:] {
+		if ps.Decommission != nil {
+			t.Errorf("decommission status of %s = %+v, want cleared", ps.Name, ps.Decommission)
+		}
+	}
+	if fields := minioStatusFields(&minio.Status); len(fields.PoolStatus) != 1 || fields.PoolStatus[0].Name != "pool-0" {
+		t.Errorf("status fields pools = %+v, want only pool-0", fields.PoolStatus)
+	}
+}
+
+func TestCheckPVCReusesOrphanedPVC(t *testing.T) {
+	minio := newTestMinIO(4, 1)
+	pool := &minio.Spec.Pools[0]
+	orphaned := newTestPVC(minio, pool, 0, nil, "10Gi")
+	orphaned.Labels = map[string]string{miniov1alpha1.OrphanedLabel: "true"}
+
+	r := newTestReconciler(t, []client.Object{orphaned})
+	if err := r.checkPVC(context.TODO(), minio, pool); err != nil {
+		t.Fatal(err)
+	}
+	var found corev1.PersistentVolumeClaim
+	if err := r.Get(context.TODO(), client.ObjectKeyFromObject(orphaned), &found); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := found.Labels[miniov1alpha1.OrphanedLabel]; ok || found.Labels[miniov1alpha1.PoolLabel] != pool.Name || found.Labels[miniov1alpha1.MinIOLable] != minio.Name {
+		t.Errorf("PVC labels = %v, want pool labels restored", found.Labels)
+	}
+	if events := recordedEvents(r); len(events) != 1 || !strings.Contains(events[0], "PVCReused") {
+		t.Errorf("events = %v, want PVCReused", events)
+	}
+}
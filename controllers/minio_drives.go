@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/minio/madmin-go/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 通过管理接口获取每个服务及卷的状态并记录到服务池状态中，服务或卷离线时产生 Warning 事件
+func (r *MinIOHealthCheckerReconciler) checkServers(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	credentials, err := getMinIOCredentials(ctx, r.KubeClient, minio)
+	if err != nil {
+		return err
+	}
+	tr, err := newMinIOTransport(ctx, r.KubeClient, minio)
+	if err != nil {
+		return err
+	}
+	adminClnt, err := minio.NewMinIOAdmin(credentials, tr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	info, err := adminClnt.ServerInfo(ctx)
+	if err != nil {
+		return err
+	}
+	storage, err := adminClnt.StorageInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	servers := make(map[string]*miniov1alpha1.MinIOServer, len(info.Servers))
+	server := func(name string) *miniov1alpha1.MinIOServer {
+		if _, ok := servers[name]; !ok {
+			servers[name] = &miniov1alpha1.MinIOServer{Name: name}
+		}
+		return servers[name]
+	}
+	for _, props := range info.Servers {
+		s := server(endpointPodName(props.Endpoint))
+		s.State = props.State
+		s.Uptime = props.Uptime
+		s.Version = props.Version
+	}
+	for _, disk := range storage.Disks {
+		podName := endpointPodName(disk.Endpoint)
+		drive := miniov1alpha1.DriveStatus{
+			Path:       driveEndpointPath(disk),
+			State:      disk.State,
+			TotalSpace: int64(disk.TotalSpace),
+			UsedSpace:  int64(disk.UsedSpace),
+			Healing:    disk.Healing,
+		}
+		drive.PVC = drivePVCName(minio, podName, drive.Path)
+		s := server(podName)
+		s.Drives = append(s.Drives, drive)
+	}
+
+	r.recordOfflineServers(minio, servers)
+	setServerHealth(minio, servers)
+
+	return nil
+}
+
+// 服务或卷由在线变为离线时产生 Warning 事件
+func (r *MinIOHealthCheckerReconciler) recordOfflineServers(minio *miniov1alpha1.MinIO, servers map[string]*miniov1alpha1.MinIOServer) {
+	previous := make(map[string]string)
+	for _, ps := range minio.Status.PoolStatus {
+		for _, s := range ps.Servers {
+			previous[s.Name] = s.State
+			for _, drive := range s.Drives {
+				previous[s.Name+":"+drive.Path] = drive.State
+			}
+		}
+	}
+
+	for name, s := range servers {
+		if s.State == string(madmin.ItemOffline) && previous[name] != s.State {
+			r.Recorder.Eventf(minio, corev1.EventTypeWarning, "ServerOffline", "MinIO server in Pod %s is offline", name)
+		}
+		for _, drive := range s.Drives {
+			if drive.State == madmin.DriveStateOffline && previous[name+":"+drive.Path] != drive.State {
+				r.Recorder.Eventf(minio, corev1.EventTypeWarning, "DriveOffline", "Drive %s of Pod %s is offline, PVC %s", drive.Path, name, drive.PVC)
+			}
+		}
+	}
+}
+
+// 将管理接口返回的服务及卷的状态写入服务池状态，其他字段由 MinIOStatusReconciler 维护
+func setServerHealth(minio *miniov1alpha1.MinIO, servers map[string]*miniov1alpha1.MinIOServer) {
+	for i := range minio.Status.PoolStatus {
+		for j := range minio.Status.PoolStatus[i].Servers {
+			s := &minio.Status.PoolStatus[i].Servers[j]
+			s.State, s.Uptime, s.Version, s.Drives = "", 0, "", nil
+		}
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pool, _, ok := minio.PoolServer(name)
+		if !ok {
+			continue
+		}
+		health := servers[name]
+		sort.Slice(health.Drives, func(i, j int) bool { return health.Drives[i].Path < health.Drives[j].Path })
+
+		ps := poolStatusEntry(minio, pool.Name)
+		var s *miniov1alpha1.MinIOServer
+		for j := range ps.Servers {
+			if ps.Servers[j].Name == name {
+				s = &ps.Servers[j]
+				break
+			}
+		}
+		if s == nil {
+			ps.Servers = append(ps.Servers, miniov1alpha1.MinIOServer{Name: name})
+			s = &ps.Servers[len(ps.Servers)-1]
+		}
+		s.State, s.Uptime, s.Version, s.Drives = health.State, health.Uptime, health.Version, health.Drives
+	}
+}
+
+// 返回服务池的状态，不存在时添加
+func poolStatusEntry(minio *miniov1alpha1.MinIO, name string) *miniov1alpha1.PoolStatus {
+	for i := range minio.Status.PoolStatus {
+		if minio.Status.PoolStatus[i].Name == name {
+			return &minio.Status.PoolStatus[i]
+		}
+	}
+	minio.Status.PoolStatus = append(minio.Status.PoolStatus, miniov1alpha1.PoolStatus{Name: name})
+	return &minio.Status.PoolStatus[len(minio.Status.PoolStatus)-1]
+}
+
+// 从 MinIO 返回的地址中取出 Pod 名称，地址形如 minio-ss-pool-0.miniohl.default.svc.cluster.local:9000
+// 或 http://minio-ss-pool-0.miniohl.default.svc.cluster.local:9000/data
+func endpointPodName(endpoint string) string {
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(endpoint); err == nil {
+		host = h
+	}
+	return strings.SplitN(host, ".", 2)[0]
+}
+
+// 返回 Pod 中挂载在 path 的卷对应的 PVC 名称，无法对应时返回空字符串
+func drivePVCName(minio *miniov1alpha1.MinIO, podName, path string) string {
+	pool, index, ok := minio.PoolServer(podName)
+	if !ok {
+		return ""
+	}
+	volume, ok := minio.PoolVolumeIndex(pool, path)
+	if !ok {
+		return ""
+	}
+	return utils.PersistentVolumeClaimName(minio, pool, index, volume)
+}
+
+// 返回卷在 Pod 中的挂载路径
+func driveEndpointPath(disk madmin.Disk) string {
+	if u, err := url.Parse(disk.Endpoint); err == nil && u.Host != "" {
+		return u.Path
+	}
+	return disk.DrivePath
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	"github.com/minio/madmin-go/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDrivesTestMinIO() *miniov1alpha1.MinIO {
+	return &miniov1alpha1.MinIO{
+		ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "default"},
+		Spec: miniov1alpha1.MinIOSpec{
+			Pools: []miniov1alpha1.Pool{
+				{Name: "pool-0", Servers: 4, VolumesPerServer: 1},
+				{Name: "pool-1", Servers: 4, VolumesPerServer: 4},
+			},
+		},
+	}
+}
+
+func TestEndpointPodName(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"minio-ss-pool-0-1.miniohl.default.svc.cluster.local:9000", "minio-ss-pool-0-1"},
+		{"http://minio-ss-pool-0-1.miniohl.default.svc.cluster.local:9000/export", "minio-ss-pool-0-1"},
+		{"https://minio-ss-pool-1-3.miniohl.default.svc.cluster.local:9000/export-2", "minio-ss-pool-1-3"},
+		{"https://minio-ss-pool-1-3.miniohl.default.svc.cluster.local/export-2", "minio-ss-pool-1-3"},
+		{"minio-ss-pool-0-2:9000", "minio-ss-pool-0-2"},
+		{"minio-ss-pool-0-2", "minio-ss-pool-0-2"},
+	}
+	for _, tt := range tests {
+		if got := endpointPodName(tt.endpoint); got != tt.want {
+			t.Errorf("endpointPodName(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestDriveEndpointPath(t *testing.T) {
+	tests := []struct {
+		name string
+		disk madmin.Disk
+		want string
+	}{
+		{
+			name: "url endpoint with single volume",
+			disk: madmin.Disk{Endpoint: "http://minio-ss-pool-0-1.miniohl.default.svc.cluster.local:9000/export", DrivePath: "/ignored"},
+			want: "/export",
+		},
+		{
+			name: "url endpoint with multiple volumes",
+			disk: madmin.Disk{Endpoint: "https://minio-ss-pool-1-0.miniohl.default.svc.cluster.local:9000/export-3"},
+			want: "/export-3",
+		},
+		{
+			name: "host:port endpoint uses drive path",
+			disk: madmin.Disk{Endpoint: "minio-ss-pool-0-1.miniohl.default.svc.cluster.local:9000", DrivePath: "/export"},
+			want: "/export",
+		},
+		{
+			name: "local path endpoint uses drive path",
+			disk: madmin.Disk{Endpoint: "/export-1", DrivePath: "/export-1"},
+			want: "/export-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := driveEndpointPath(tt.disk); got != tt.want {
+				t.Errorf("driveEndpointPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrivePVCName(t *testing.T) {
+	minio := newDrivesTestMinIO()
+	tests := []struct {
+		name    string
+		podName string
+		path    string
+		want    string
+	}{
+		{"single volume", "minio-ss-pool-0-2", "/export", "data0-minio-ss-pool-0-2"},
+		{"single volume with index suffix", "minio-ss-pool-0-2", "/export-0", ""},
+		{"multiple volumes", "minio-ss-pool-1-3", "/export-2", "data2-minio-ss-pool-1-3"},
+		{"volume index out of range", "minio-ss-pool-1-3", "/export-4", ""},
+		{"multiple volumes without index", "minio-ss-pool-1-3", "/export", ""},
+		{"unknown pool", "minio-ss-pool-2-0", "/export", ""},
+		{"other MinIO", "other-ss-pool-0-0", "/export", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := drivePVCName(minio, tt.podName, tt.path); got != tt.want {
+				t.Errorf("drivePVCName(%q, %q) = %q, want %q", tt.podName, tt.path, got, tt.want)
+			}
+		})
+	}
+
+	minio.Spec.Mountpath = "/data/"
+	if got := drivePVCName(minio, "minio-ss-pool-0-0", "/data"); got != "data0-minio-ss-pool-0-0" {
+		t.Errorf("drivePVCName with custom mount path = %q", got)
+	}
+}
+
+func TestSetServerHealth(t *testing.T) {
+	minio := newDrivesTestMinIO()
+	minio.Status.PoolStatus = []miniov1alpha1.PoolStatus{
+		{
+			Name: "pool-0",
+			Servers: []miniov1alpha1.MinIOServer{
+				{Name: "minio-ss-pool-0-0", State: "online", Version: "old", Drives: []miniov1alpha1.DriveStatus{{Path: "/export", State: "ok"}}},
+				{Name: "minio-ss-pool-0-1", State: "online"},
+			},
+		},
+	}
+	servers := map[string]*miniov1alpha1.MinIOServer{
+		"minio-ss-pool-0-0": {
+			Name:    "minio-ss-pool-0-0",
+			State:   "online",
+			Version: "new",
+			Drives:  []miniov1alpha1.DriveStatus{{Path: "/export", State: madmin.DriveStateOffline}},
+		},
+		"minio-ss-pool-1-0": {
+			Name:  "minio-ss-pool-1-0",
+			State: "online",
+			Drives: []miniov1alpha1.DriveStatus{
+				{Path: "/export-1", State: madmin.DriveStateOk},
+				{Path: "/export-0", State: madmin.DriveStateOk},
+			},
+		},
+		// 不属于任何服务池的服务被忽略
+		"unknown-0": {Name: "unknown-0", State: "online"},
+	}
+
+	setServerHealth(minio, servers)
+
+	if len(minio.Status.PoolStatus) != 2 {
+		t.Fatalf("expected 2 pool status, got %d", len(minio.Status.PoolStatus))
+	}
+	pool0 := minio.Status.PoolStatus[0]
+	if pool0.Name != "pool-0" || len(pool0.Servers) != 2 {
+		t.Fatalf("unexpected pool-0 status %+v", pool0)
+	}
+	if s := pool0.Servers[0]; s.Version != "new" || len(s.Drives) != 1 || s.Drives[0].State != madmin.DriveStateOffline {
+		t.Errorf("server minio-ss-pool-0-0 not updated, %+v", s)
+	}
+	// 管理接口未返回的服务清空上一次的状态
+	if s := pool0.Servers[1]; s.State != "" || s.Drives != nil {
+		t.Errorf("server minio-ss-pool-0-1 should be reset, %+v", s)
+	}
+
+	pool1 := minio.Status.PoolStatus[1]
+	if pool1.Name != "pool-1" || len(pool1.Servers) != 1 || pool1.Servers[0].Name != "minio-ss-pool-1-0" {
+		t.Fatalf("unexpected pool-1 status %+v", pool1)
+	}
+	if drives := pool1.Servers[0].Drives; len(drives) != 2 || drives[0].Path != "/export-0" || drives[1].Path != "/export-1" {
+		t.Errorf("drives of minio-ss-pool-1-0 not sorted by path, %+v", drives)
+	}
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// 等待 MinIO 服务停止的重试间隔
+const deletionRequeueInterval = 5 * time.Second
+
+// 为 MinIO 实例添加 finalizer，返回 true 表示本次新增了 finalizer
+func (r *MinIOReconciler) ensureFinalizer(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+	if controllerutil.ContainsFinalizer(minio, miniov1alpha1.MinIOFinalizer) {
+		return false, nil
+	}
+	controllerutil.AddFinalizer(minio, miniov1alpha1.MinIOFinalizer)
+	if err := r.Update(ctx, minio); err != nil {
+		klog.Errorf("add finalizer to MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// 删除 MinIO 实例: 停止所有服务池的 Pod，按 ReclaimStorage 删除或保留 PVC 及配置 Secret，最后移除 finalizer
+func (r *MinIOReconciler) handleDeletion(ctx context.Context, minio *miniov1alpha1.MinIO) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(minio, miniov1alpha1.MinIOFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	stopped, err := r.stopServers(ctx, minio)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !stopped {
+		return ctrl.Result{RequeueAfter: deletionRequeueInterval}, nil
+	}
+
+	if err := r.reclaimPVCs(ctx, minio, minio.MinIOPodLabels()); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reclaimConfigurationSecret(ctx, minio); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// CSR 为集群级别的资源，不会随 MinIO 实例被回收
+	if err := r.deleteCSR(ctx, minio); err != nil {
+		return ctrl.Result{}, err
+	}
+	clearCertificateExpiry(minio)
+
+	controllerutil.RemoveFinalizer(minio, miniov1alpha1.MinIOFinalizer)
+	if err := r.Update(ctx, minio); err != nil {
+		klog.Errorf("remove finalizer from MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Event(minio, corev1.EventTypeNormal, "FinalizerRemoved", "MinIO cleanup finished")
+
+	return ctrl.Result{}, nil
+}
+
+// 将所有服务池的 StatefulSet 缩容到 0，返回 true 表示所有 Pod 都已退出
+func (r *MinIOReconciler) stopServers(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+	// 包括正在下线、已从 Spec.Pools 中移除的服务池
+	statefulSets, err := r.listStatefulSets(ctx, minio)
+	if err != nil {
+		return false, err
+	}
+	for i := range statefulSets {
+		ss := &statefulSets[i]
+		if ss.Spec.Replicas != nil && *ss.Spec.Replicas == 0 {
+			continue
+		}
+
+		var replicas int32
+		ss.Spec.Replicas = &replicas
+		if err := r.Update(ctx, ss); err != nil {
+			return false, err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ServersStopping", "Stopping MinIO servers of pool %s", ss.Labels[miniov1alpha1.PoolLabel])
+	}
+
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPodLabels())); err != nil {
+		klog.Errorf("query Pod list error, %s", err)
+		return false, err
+	}
+	if len(podList.Items) > 0 {
+		klog.Infof("waiting for %d MinIO Pods of %s/%s to stop", len(podList.Items), minio.Namespace, minio.Name)
+		return false, nil
+	}
+	r.Recorder.Event(minio, corev1.EventTypeNormal, "ServersStopped", "All MinIO servers stopped")
+
+	return true, nil
+}
+
+// 处理 labels 匹配的 PVC，ReclaimStorage 为 true 时删除 PVC，否则解除 PVC 与 MinIO 实例的关联并添加标记，以便之后复用
+// 保留的 PVC 会移除 MinIO 实例及服务池的 Labels，不再出现在 PVCStatus 中
+func (r *MinIOReconciler) reclaimPVCs(ctx context.Context, minio *miniov1alpha1.MinIO, labels map[string]string) error {
+	var pvcList corev1.PersistentVolumeClaimList
+	if err := r.List(ctx, &pvcList, client.InNamespace(minio.Namespace), client.MatchingLabels(labels)); err != nil {
+		klog.Errorf("query PVC list error, %s", err)
+		return err
+	}
+
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if minio.Spec.ReclaimStorage {
+			if !pvc.DeletionTimestamp.IsZero() {
+				continue
+			}
+			if err := r.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+				klog.Errorf("delete PVC %s/%s error, %s", pvc.Namespace, pvc.Name, err)
+				return err
+			}
+			r.Recorder.Eventf(minio, corev1.EventTypeNormal, "PVCDeleted", "PVC %s deleted", pvc.Name)
+			continue
+		}
+
+		utils.SyncPersistentVolumeClaimOwner(minio, pvc)
+		delete(pvc.Labels, miniov1alpha1.MinIOLable)
+		delete(pvc.Labels, miniov1alpha1.PoolLabel)
+		pvc.Labels[miniov1alpha1.OrphanedLabel] = "true"
+		if err := r.Update(ctx, pvc); err != nil {
+			klog.Errorf("orphan PVC %s/%s error, %s", pvc.Namespace, pvc.Name, err)
+			return err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "PVCOrphaned", "PVC %s retained for later reuse", pvc.Name)
+	}
+
+	return nil
+}
+
+// ReclaimStorage 为 false 时保留自动生成的配置 Secret，解除与 MinIO 实例的关联并添加与 PVC 相同的标记，
+// 重新创建同名实例时继续使用原有的 root 凭证访问保留的数据；为 true 时 Secret 随 MinIO 实例一起删除
+func (r *MinIOReconciler) reclaimConfigurationSecret(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	if minio.Spec.ReclaimStorage || minio.HasConfigurationSecret() {
+		return nil
+	}
+	secret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minio.ConfigurationSecretName(), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("get configuration Secret %s/%s error, %s", minio.Namespace, minio.ConfigurationSecretName(), err)
+		return err
+	}
+	if secret.Labels[miniov1alpha1.OrphanedLabel] == "true" {
+		return nil
+	}
+
+	var ownerRefs []metav1.OwnerReference
+	for _, ref := range secret.OwnerReferences {
+		if ref.UID != minio.UID {
+			ownerRefs = append(ownerRefs, ref)
+		}
+	}
+	secret.OwnerReferences = ownerRefs
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	delete(secret.Labels, miniov1alpha1.MinIOLable)
+	secret.Labels[miniov1alpha1.OrphanedLabel] = "true"
+	if _, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("orphan configuration Secret %s/%s error, %s", secret.Namespace, secret.Name, err)
+		return err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ConfigurationOrphaned", "Configuration Secret %s retained for later reuse", secret.Name)
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReclaimPVCs(t *testing.T) {
+	for _, reclaimStorage := range []bool{true, false} {
+		minio := newTestMinIO(2, 1)
+		minio.Spec.ReclaimStorage = reclaimStorage
+		pool := &minio.Spec.Pools[0]
+		var objs []client.Object
+		for i := 0; i < pool.Servers; i++ {
+			pvc := newTestPVC(minio, pool, i, nil, "10Gi")
+			pvc.OwnerReferences = minio.OwnerRef()
+			objs = append(objs, pvc)
+		}
+
+		r := newTestReconciler(t, objs)
+		if err := r.reclaimPVCs(context.TODO(), minio, minio.MinIOPodLabels()); err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range objs {
+			var found corev1.PersistentVolumeClaim
+			err := r.Get(context.TODO(), client.ObjectKeyFromObject(obj), &found)
+			if reclaimStorage {
+				if !errors.IsNotFound(err) {
+					t.Errorf("PVC %s was not deleted with ReclaimStorage, %v", obj.GetName(), err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found.OwnerReferences) != 0 || found.Labels[miniov1alpha1.OrphanedLabel] != "true" {
+				t.Errorf("PVC %s = %v %v, want orphaned", found.Name, found.OwnerReferences, found.Labels)
+			}
+			if _, ok := found.Labels[miniov1alpha1.MinIOLable]; ok {
+				t.Errorf("PVC %s labels = %v, want MinIO labels removed", found.Name, found.Labels)
+			}
+		}
+	}
+}
+
+func newTestConfigurationSecret(minio *miniov1alpha1.MinIO) *corev1.Secret {
+	secret, _ := utils.NewConfigurationSecretForMinIO(minio)
+	secret.OwnerReferences = append(secret.OwnerReferences, metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"})
+	return secret
+}
+
+func TestReclaimConfigurationSecret(t *testing.T) {
+	tests := []struct {
+		name           string
+		reclaimStorage bool
+		configuration  *corev1.LocalObjectReference
+		wantOrphaned   bool
+	}{
+		{name: "retained with the PVCs", wantOrphaned: true},
+		{name: "deleted with the instance", reclaimStorage: true},
+		{name: "user Secret is untouched", configuration: &corev1.LocalObjectReference{Name: "minio-configuration"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO(4, 1)
+			minio.Spec.ReclaimStorage = tt.reclaimStorage
+			secret := newTestConfigurationSecret(minio)
+			minio.Spec.Configuration = tt.configuration
+
+			r := newTestReconciler(t, nil, secret)
+			if err := r.reclaimConfigurationSecret(context.TODO(), minio); err != nil {
+				t.Fatal(err)
+			}
+			found, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if orphaned := found.Labels[miniov1alpha1.OrphanedLabel] == "true"; orphaned != tt.wantOrphaned {
+				t.Errorf("orphaned = %v, want %v", orphaned, tt.wantOrphaned)
+			}
+			if owned := metav1.IsControlledBy(found, minio); owned == tt.wantOrphaned {
+				t.Errorf("owned by MinIO = %v, want %v", owned, !tt.wantOrphaned)
+			}
+			if len(found.OwnerReferences) == 0 || found.OwnerReferences[len(found.OwnerReferences)-1].UID != "other-uid" {
+				t.Errorf("ownerReferences = %v, want other owners kept", found.OwnerReferences)
+			}
+		})
+	}
+
+	t.Run("missing Secret", func(t *testing.T) {
+		r := newTestReconciler(t, nil)
+		if err := r.reclaimConfigurationSecret(context.TODO(), newTestMinIO(4, 1)); err != nil {
+			t.Errorf("reclaimConfigurationSecret() = %v, want nil", err)
+		}
+	})
+}
+
+func TestCheckConfigurationSecretReusesOrphanedSecret(t *testing.T) {
+	minio := newTestMinIO(4, 1)
+	secret := newTestConfigurationSecret(minio)
+	r := newTestReconciler(t, nil, secret)
+	if err := r.reclaimConfigurationSecret(context.TODO(), minio); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新创建的同名实例 UID 不同
+	recreated := newTestMinIO(4, 1)
+	recreated.UID = "recreated-uid"
+	if err := r.checkConfigurationSecret(context.TODO(), recreated); err != nil {
+		t.Fatal(err)
+	}
+	found, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(found.Data["config.env"]) != string(secret.Data["config.env"]) {
+		t.Errorf("root credentials were regenerated")
+	}
+	if _, ok := found.Labels[miniov1alpha1.OrphanedLabel]; ok || found.Labels[miniov1alpha1.MinIOLable] != minio.Name {
+		t.Errorf("labels = %v, want MinIO labels restored", found.Labels)
+	}
+	if !metav1.IsControlledBy(found, recreated) {
+		t.Errorf("ownerReferences = %v, want controlled by the recreated MinIO", found.OwnerReferences)
+	}
+	events := recordedEvents(r)
+	if len(events) != 2 || !strings.Contains(events[0], "ConfigurationOrphaned") || !strings.Contains(events[1], "ConfigurationReused") {
+		t.Errorf("events = %v, want ConfigurationOrphaned and ConfigurationReused", events)
+	}
+}
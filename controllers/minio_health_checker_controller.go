@@ -2,180 +2,150 @@ package controllers
 
 import (
 	"context"
-	"crypto/tls"
 	miniov1alpha1 "minio-operator/api/v1alpha1"
-	"net"
-	"net/http"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type MinIOHealthCheckerReconciler struct {
 	client.Client
-	KubeClient *kubernetes.Clientset
+	KubeClient kubernetes.Interface
 	Scheme     *runtime.Scheme
+
+	Recorder record.EventRecorder
+
+	// 同时进行健康检查的 MinIO 实例数量，由 --health-checker-concurrency 设置
+	MaxConcurrentReconciles int
+	// 健康检查的间隔，未设置时由 MONITORING_INTERVAL 环境变量决定
+	Interval time.Duration
+
+	statusWriter *statusWriter
+	// 每个 MinIO 实例最近一次进行健康检查的时间
+	lastProbe sync.Map
 }
 
-// 检查 MinIO 服务的健康状态
+// 按 Interval 定期检查 MinIO 服务的健康状态，两次检查之间保留上一次的结果
+// 服务池不可用时同样进行检查，丢失服务节点后 Healthy 会及时反映 MinIO 的实际状态
 func (r *MinIOHealthCheckerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var minio miniov1alpha1.MinIO
 	if err := r.Get(ctx, req.NamespacedName, &minio); err != nil {
+		// MinIO 实例已删除
 		if errors.IsNotFound(err) {
-			return ctrl.Result{}, err
+			r.lastProbe.Delete(req.NamespacedName)
 		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-
-	minio.Status.HealthStatus = miniov1alpha1.HealthStatusUnknown
-	if err := r.updateMinIOStatus(ctx, &minio); err != nil {
-		return ctrl.Result{Requeue: true}, err
+	previous := meta.FindStatusCondition(minio.Status.Conditions, miniov1alpha1.ConditionHealthy)
+	wasHealthy := previous != nil && previous.Status == metav1.ConditionTrue
+	// 首次部署完成前检查失败属于预期情况
+	deploying := !meta.IsStatusConditionTrue(minio.Status.Conditions, miniov1alpha1.ConditionAvailable) &&
+		(previous == nil || previous.Reason == "NotAvailable")
+
+	// Pod 等资源变化触发的调谐不会提前进行健康检查
+	if last, ok := r.lastProbe.Load(req.NamespacedName); ok {
+		if remaining := r.Interval - time.Since(last.(time.Time)); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
 	}
+	probeTime := time.Now()
 
-	// 只能在部署完成后，MinIO 服务 pod 处于 Running 状态才能进行健康检查
-	if minio.Status.Status != miniov1alpha1.DeployStatusCompleted {
-		return ctrl.Result{Requeue: true}, nil
+	// 健康检查与查询服务信息各自最多等待 healthCheckTimeout，并行进行以减少占用 worker 的时间
+	type probeResult struct {
+		healthy bool
+		err     error
 	}
-
-	var healthStatus miniov1alpha1.HealthStatus
-	if minio.MinIOHealthCheck(r.createTransport()) {
-		healthStatus = miniov1alpha1.HealthStatusHealth
-	} else {
-		healthStatus = miniov1alpha1.HealthStatusUnHealth
+	probe := make(chan probeResult, 1)
+	go func(minio *miniov1alpha1.MinIO) {
+		healthy, err := minioHealthCheck(ctx, r.KubeClient, minio)
+		probe <- probeResult{healthy: healthy, err: err}
+	}(minio.DeepCopy())
+	// 获取失败时保留上一次的服务及卷状态
+	if err := r.checkServers(ctx, &minio); err != nil {
+		klog.Errorf("query server info of MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
 	}
-	minio.Status.HealthStatus = healthStatus
-	if err := r.updateMinIOStatus(ctx, &minio); err != nil {
-		return ctrl.Result{Requeue: true}, err
+	result := <-probe
+
+	healthy, err := result.healthy, result.err
+	switch {
+	case deploying && !healthy:
+		setCondition(&minio, miniov1alpha1.ConditionHealthy, metav1.ConditionUnknown, "NotAvailable", "Waiting for all pools to be available")
+	case err != nil:
+		// 记录证书校验失败等导致无法完成健康检查的原因
+		klog.Errorf("health check of MinIO %s/%s error, %s", minio.Namespace, minio.Name, err)
+		setCondition(&minio, miniov1alpha1.ConditionHealthy, metav1.ConditionFalse, "HealthCheckError", err.Error())
+	case healthy:
+		setCondition(&minio, miniov1alpha1.ConditionHealthy, metav1.ConditionTrue, "HealthCheckPassed", "MinIO is healthy")
+	default:
+		setCondition(&minio, miniov1alpha1.ConditionHealthy, metav1.ConditionFalse, "HealthCheckFailed", "MinIO reports unhealthy, write quorum not available")
 	}
-
-	if minio.Status.HealthStatus != miniov1alpha1.HealthStatusHealth {
-		time.Sleep(time.Second)
-		return ctrl.Result{Requeue: true}, nil
+	if err := r.statusWriter.Apply(ctx, &minio); err != nil {
+		return ctrl.Result{}, err
+	}
+	// 状态写入成功后才记录检查时间，写入失败时重试不会被间隔推迟
+	r.lastProbe.Store(req.NamespacedName, probeTime)
+	if wasHealthy && !healthy {
+		r.Recorder.Eventf(&minio, corev1.EventTypeWarning, "MinIOUnhealthy", "MinIO health check failed, %s", meta.FindStatusCondition(minio.Status.Conditions, miniov1alpha1.ConditionHealthy).Message)
+	} else if !wasHealthy && healthy {
+		r.Recorder.Event(&minio, corev1.EventTypeNormal, "MinIOHealthy", "MinIO is healthy")
 	}
 
-	return ctrl.Result{}, nil
+	// 加入随机抖动，避免大量实例同时进行健康检查
+	return ctrl.Result{RequeueAfter: wait.Jitter(r.Interval, 0.1)}, nil
 }
 
-func (r *MinIOHealthCheckerReconciler) updateMinIOStatus(ctx context.Context, minio *miniov1alpha1.MinIO) error {
-	// if minio.Status.Status == miniov1alpha1.DeployStatusCompleted && minio.Status.AvailableReplicas == minio.Spec.Servers {
-	// 	return nil
-	// }
-
-	minioCopy := minio.DeepCopy()
-	minioCopy.Spec = miniov1alpha1.MinIOSpec{}
-	minioCopy.Status = minio.Status
-
-	if err := r.Status().Update(ctx, minioCopy); err != nil {
-		if errors.IsConflict(err) {
-			klog.Infof("Hit conflict issue, getting latest version of MinIO %s", minio.Name)
-			err = r.Get(ctx, client.ObjectKeyFromObject(minio), minioCopy)
-			if err != nil {
-				return err
+// MinIOHealthCheckerReconciler 负责的状态字段
+func healthCheckerFields(status *miniov1alpha1.MinIOStatus) miniov1alpha1.MinIOStatus {
+	fields := miniov1alpha1.MinIOStatus{
+		Conditions: filterConditions(status.Conditions, miniov1alpha1.ConditionHealthy),
+	}
+	// 服务及卷的状态，服务池的其他状态由 MinIOStatusReconciler 维护
+	for _, ps := range status.PoolStatus {
+		var servers []miniov1alpha1.MinIOServer
+		for _, s := range ps.Servers {
+			if s.State == "" && len(s.Drives) == 0 {
+				continue
 			}
-			return r.updateMinIOStatus(ctx, minioCopy)
+			servers = append(servers, miniov1alpha1.MinIOServer{
+				Name:    s.Name,
+				State:   s.State,
+				Uptime:  s.Uptime,
+				Version: s.Version,
+				Drives:  s.Drives,
+			})
+		}
+		if len(servers) > 0 {
+			fields.PoolStatus = append(fields.PoolStatus, miniov1alpha1.PoolStatus{Name: ps.Name, Servers: servers})
 		}
-		return err
-	}
-	return nil
-}
-
-// 创建 transport
-func (r *MinIOHealthCheckerReconciler) createTransport() *http.Transport {
-	// rootCAs := c.fetchTransportCACertificates()
-	dialer := &net.Dialer{
-		Timeout:   15 * time.Second,
-		KeepAlive: 15 * time.Second,
-	}
-	transport := &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		DialContext:           dialer.DialContext,
-		MaxIdleConnsPerHost:   1024,
-		IdleConnTimeout:       15 * time.Second,
-		ResponseHeaderTimeout: 15 * time.Minute,
-		TLSHandshakeTimeout:   15 * time.Second,
-		ExpectContinueTimeout: 15 * time.Second,
-		// Go net/http automatically unzip if content-type is
-		// gzip disable this feature, as we are always interested
-		// in raw stream.
-		DisableCompression: true,
-		TLSClientConfig: &tls.Config{
-			// Can't use SSLv3 because of POODLE and BEAST
-			// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
-			// Can't use TLSv1.1 because of RC4 cipher usage
-			MinVersion: tls.VersionTLS12,
-			// RootCAs:    rootCAs,
-		},
 	}
-
-	return transport
+	return fields
 }
 
-// 合并 env 和 config.env 中的配置的环境变量
-// func (r *MinIOHealthCheckerReconciler) getMinIOCredentials(ctx context.Context, minio *miniov1alpha1.MinIO) (map[string][]byte, error) {
-// 	minioConfiguration := map[string][]byte{}
-//
-// 	// 查询 env 中的配置的环境变量
-// 	for _, config := range minio.GetEnvVars() {
-// 		minioConfiguration[config.Name] = []byte(config.Value)
-// 	}
-//
-// 	// 加载 config.env 中的配置的环境变量
-// 	config, err := r.getMinIOConfiguration(ctx, minio)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	for key, val := range config {
-// 		minioConfiguration[key] = val
-// 	}
-//
-// 	var accessKey string
-// 	var secretKey string
-//
-// 	if _, ok := minioConfiguration["accesskey"]; ok {
-// 		accessKey = string(minioConfiguration["accesskey"])
-// 	}
-//
-// 	if _, ok := minioConfiguration["secretkey"]; ok {
-// 		secretKey = string(minioConfiguration["secretkey"])
-// 	}
-//
-// 	if accessKey == "" || secretKey == "" {
-// 		return minioConfiguration, ErrEmptyRootCredentials
-// 	}
-//
-// 	return minioConfiguration, nil
-// }
-//
-// // 查询 config.env 中的配置的环境变量
-// func (r *MinIOHealthCheckerReconciler) getMinIOConfiguration(ctx context.Context, minio *miniov1alpha1.MinIO) (map[string][]byte, error) {
-// 	config := map[string][]byte{}
-// 	// Load tenant configuration from file
-// 	if minio.HasConfigurationSecret() {
-// 		minioConfigurationSecretName := minio.Spec.Configuration.Name
-// 		minioConfigurationSecret, err := r.KubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, minioConfigurationSecretName, metav1.GetOptions{})
-// 		if err != nil {
-// 			return nil, err
-// 		}
-// 		configFromFile := utils.ParseRawConfiguration(minioConfigurationSecret.Data["config.env"])
-// 		for key, val := range configFromFile {
-// 			config[key] = val
-// 		}
-// 	}
-// 	return config, nil
-// }
-
 func (r *MinIOHealthCheckerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.statusWriter = newStatusWriter(mgr.GetClient(), healthCheckerFieldManager, healthCheckerFields)
+	if r.Interval <= 0 {
+		r.Interval = miniov1alpha1.GetMonitoringInterval()
+	}
 	return ctrl.NewControllerManagedBy(mgr).
+		Named("minio-health-checker").
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		For(&miniov1alpha1.MinIO{}).
-		For(&corev1.Pod{}).
-		For(&corev1.Service{}).
-		// For(&corev1.PersistentVolumeClaim{}).
-		// For(&corev1.Secret{}).
+		Owns(&corev1.Service{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(minioForObject)).
 		Complete(r)
 }
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 状态提交总是失败的 client
+type failingStatusClient struct {
+	client.Client
+}
+
+func (c *failingStatusClient) Status() client.StatusWriter {
+	return &failingStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type failingStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *failingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return errors.New("apply failed")
+}
+
+func TestHealthCheckerRetriesFailedStatusApply(t *testing.T) {
+	minio := newTestMinIO(4, 1)
+	base := newTestReconciler(t, []client.Object{minio})
+	key := types.NamespacedName{Namespace: minio.Namespace, Name: minio.Name}
+	r := &MinIOHealthCheckerReconciler{
+		Client:     base.Client,
+		KubeClient: base.KubeClient,
+		Scheme:     base.Scheme,
+		Recorder:   record.NewFakeRecorder(10),
+		Interval:   time.Hour,
+	}
+
+	// 状态写入失败时不记录检查时间，下一次调谐立即重新检查
+	r.statusWriter = newStatusWriter(&failingStatusClient{Client: base.Client}, healthCheckerFieldManager, healthCheckerFields)
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err == nil {
+		t.Fatalf("Reconcile returned no error for a failed status apply")
+	}
+	if _, ok := r.lastProbe.Load(key); ok {
+		t.Errorf("probe time recorded after a failed status apply")
+	}
+
+	r.statusWriter = newStatusWriter(&patchCountingClient{Client: base.Client}, healthCheckerFieldManager, healthCheckerFields)
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.lastProbe.Load(key); !ok {
+		t.Errorf("probe time not recorded after a successful status apply")
+	}
+	result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > r.Interval {
+		t.Errorf("requeueAfter = %s, want the remaining interval", result.RequeueAfter)
+	}
+}
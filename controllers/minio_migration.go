@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 将直接创建 Pod 部署的服务池迁移为由 StatefulSet 管理
+// 旧 Pod 会被删除，旧 PVC 绑定的 PV 会重新绑定到按 StatefulSet 规则命名的 PVC 上，数据不会丢失
+// 返回 true 表示服务池中已没有旧的 Pod 和 PVC，可以创建 StatefulSet
+func (r *MinIOReconciler) migrateLegacyPool(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) (bool, error) {
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPoolLabels(pool))); err != nil {
+		klog.Errorf("query Pod list of pool %s error, %s", pool.Name, err)
+		return false, err
+	}
+
+	legacyPods := 0
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil || owner.Kind != miniov1alpha1.MinIOCRDResourceKind {
+			continue
+		}
+		legacyPods++
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		klog.Infof("delete legacy MinIO Pod %s/%s", pod.Namespace, pod.Name)
+		if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "LegacyPodDeleted", "MinIO Pod %s deleted, pool %s is migrating to StatefulSet", pod.Name, pool.Name)
+	}
+	// PVC 被 Pod 使用时无法删除，需要等待旧 Pod 全部退出
+	if legacyPods > 0 {
+		return false, nil
+	}
+
+	migrated := true
+	for i := 0; i < pool.Servers; i++ {
+		for j := 0; j < pool.VolumesPerServer; j++ {
+			legacyName := utils.LegacyPersistentVolumeClaimName(minio, pool, i, j)
+			name := utils.PersistentVolumeClaimName(minio, pool, i, j)
+			done, err := r.migrateLegacyPVC(ctx, minio, legacyName, name)
+			if err != nil {
+				return false, err
+			}
+			if !done {
+				migrated = false
+			}
+		}
+	}
+
+	return migrated, nil
+}
+
+// 将旧 PVC 绑定的 PV 转移到新 PVC，返回 true 表示旧 PVC 已不存在
+func (r *MinIOReconciler) migrateLegacyPVC(ctx context.Context, minio *miniov1alpha1.MinIO, legacyName, name string) (bool, error) {
+	var legacy corev1.PersistentVolumeClaim
+	err := r.Get(ctx, types.NamespacedName{Namespace: minio.Namespace, Name: legacyName}, &legacy)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil {
+		if !legacy.DeletionTimestamp.IsZero() {
+			return false, nil
+		}
+
+		// 未绑定 PV 的 PVC 中没有数据，直接删除后由 StatefulSet 重新创建
+		if legacy.Spec.VolumeName != "" {
+			if err := r.retainPersistentVolume(ctx, legacy.Spec.VolumeName); err != nil {
+				return false, err
+			}
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   minio.Namespace,
+					Labels:      legacy.Labels,
+					Annotations: map[string]string{miniov1alpha1.MigratedFromAnnotation: legacyName},
+				},
+				Spec: legacy.Spec,
+			}
+			if err := r.Create(ctx, pvc); err != nil && !errors.IsAlreadyExists(err) {
+				klog.Errorf("create PVC %s/%s error: %s", pvc.Namespace, pvc.Name, err)
+				return false, err
+			}
+		}
+
+		klog.Infof("delete legacy PVC %s/%s", legacy.Namespace, legacy.Name)
+		if err := r.Delete(ctx, &legacy); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeNormal, "LegacyPVCMigrated", "PVC %s migrated to %s", legacyName, name)
+		return false, nil
+	}
+
+	var pvc corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, types.NamespacedName{Namespace: minio.Namespace, Name: name}, &pvc); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if _, ok := pvc.Annotations[miniov1alpha1.MigratedFromAnnotation]; !ok || pvc.Spec.VolumeName == "" {
+		return true, nil
+	}
+
+	var pv corev1.PersistentVolume
+	if err := r.Get(ctx, types.NamespacedName{Name: pvc.Spec.VolumeName}, &pv); err != nil {
+		return false, err
+	}
+
+	// 旧 PVC 删除后 PV 处于 Released 状态，将其预绑定到新 PVC
+	if pvc.Status.Phase != corev1.ClaimBound {
+		if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Namespace != pvc.Namespace || pv.Spec.ClaimRef.Name != pvc.Name {
+			pv.Spec.ClaimRef = &corev1.ObjectReference{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+				Namespace:  pvc.Namespace,
+				Name:       pvc.Name,
+			}
+			klog.Infof("rebind PV %s to PVC %s/%s", pv.Name, pvc.Namespace, pvc.Name)
+			if err := r.Update(ctx, &pv); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	// 绑定完成，恢复 PV 原有的回收策略
+	if policy, ok := pv.Annotations[miniov1alpha1.ReclaimPolicyAnnotation]; ok {
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimPolicy(policy)
+		delete(pv.Annotations, miniov1alpha1.ReclaimPolicyAnnotation)
+		if err := r.Update(ctx, &pv); err != nil {
+			return false, err
+		}
+	}
+	delete(pvc.Annotations, miniov1alpha1.MigratedFromAnnotation)
+	if err := r.Update(ctx, &pvc); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// 将 PV 的回收策略修改为 Retain，避免删除旧 PVC 时数据被回收
+func (r *MinIOReconciler) retainPersistentVolume(ctx context.Context, name string) error {
+	var pv corev1.PersistentVolume
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, &pv); err != nil {
+		return err
+	}
+	if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+		return nil
+	}
+
+	if pv.Annotations == nil {
+		pv.Annotations = map[string]string{}
+	}
+	pv.Annotations[miniov1alpha1.ReclaimPolicyAnnotation] = string(pv.Spec.PersistentVolumeReclaimPolicy)
+	pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+	klog.Infof("set reclaim policy of PV %s to Retain", pv.Name)
+	return r.Update(ctx, &pv)
+}
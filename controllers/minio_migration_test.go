@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testPVName = "pv-0"
+
+func newLegacyPVC(minio *miniov1alpha1.MinIO, volumeName string) *corev1.PersistentVolumeClaim {
+	pool := &minio.Spec.Pools[0]
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.LegacyPersistentVolumeClaimName(minio, pool, 0, 0),
+			Namespace: minio.Namespace,
+			Labels:    minio.MinIOPoolLabels(pool),
+			UID:       "legacy-uid",
+		},
+		Spec:   corev1.PersistentVolumeClaimSpec{VolumeName: volumeName},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}
+}
+
+func newTestPV(claim *corev1.PersistentVolumeClaim, policy corev1.PersistentVolumeReclaimPolicy) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: testPVName},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: policy,
+			ClaimRef: &corev1.ObjectReference{
+				Kind:      "PersistentVolumeClaim",
+				Namespace: claim.Namespace,
+				Name:      claim.Name,
+				UID:       claim.UID,
+			},
+		},
+	}
+}
+
+func getPV(t *testing.T, c client.Client) *corev1.PersistentVolume {
+	var pv corev1.PersistentVolume
+	if err := c.Get(context.Background(), types.NamespacedName{Name: testPVName}, &pv); err != nil {
+		t.Fatal(err)
+	}
+	return &pv
+}
+
+func getPVC(t *testing.T, c client.Client, namespace, name string) *corev1.PersistentVolumeClaim {
+	var pvc corev1.PersistentVolumeClaim
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, &pvc)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pvc
+}
+
+func TestMigrateLegacyPoolDeletesLegacyPods(t *testing.T) {
+	minio := newTestMinIO(2, 1)
+	pool := &minio.Spec.Pools[0]
+	legacy := newTestPod(minio, pool, 0, "", true)
+	legacy.OwnerReferences = minio.OwnerRef()
+	managed := newTestPod(minio, pool, 1, "", true)
+	controller := true
+	managed.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: minio.PoolStatefulSetName(pool), UID: "ss-uid", Controller: &controller}}
+	r := newTestReconciler(t, []client.Object{legacy, managed})
+
+	migrated, err := r.migrateLegacyPool(context.Background(), minio, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated {
+		t.Errorf("pool should wait for legacy Pods to exit")
+	}
+	var pods corev1.PodList
+	if err := r.List(context.Background(), &pods); err != nil {
+		t.Fatal(err)
+	}
+	if len(pods.Items) != 1 || pods.Items[0].Name != managed.Name {
+		t.Errorf("only legacy Pod should be deleted, remaining %v", pods.Items)
+	}
+}
+
+func TestMigrateLegacyPool(t *testing.T) {
+	ctx := context.Background()
+	minio := newTestMinIO(1, 1)
+	pool := &minio.Spec.Pools[0]
+	legacy := newLegacyPVC(minio, testPVName)
+	name := utils.PersistentVolumeClaimName(minio, pool, 0, 0)
+	r := newTestReconciler(t, []client.Object{legacy, newTestPV(legacy, corev1.PersistentVolumeReclaimDelete)})
+
+	reconcile := func(step string, wantMigrated bool) {
+		t.Helper()
+		migrated, err := r.migrateLegacyPool(ctx, minio, pool)
+		if err != nil {
+			t.Fatalf("%s: %s", step, err)
+		}
+		if migrated != wantMigrated {
+			t.Fatalf("%s: migrated = %v, want %v", step, migrated, wantMigrated)
+		}
+	}
+
+	reconcile("retain PV, copy and delete legacy PVC", false)
+	pv := getPV(t, r.Client)
+	if pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain || pv.Annotations[miniov1alpha1.ReclaimPolicyAnnotation] != string(corev1.PersistentVolumeReclaimDelete) {
+		t.Fatalf("PV should be retained with the original policy recorded, %v %v", pv.Spec.PersistentVolumeReclaimPolicy, pv.Annotations)
+	}
+	if getPVC(t, r.Client, minio.Namespace, legacy.Name) != nil {
+		t.Fatalf("legacy PVC should be deleted")
+	}
+	pvc := getPVC(t, r.Client, minio.Namespace, name)
+	if pvc == nil || pvc.Spec.VolumeName != testPVName || pvc.Annotations[miniov1alpha1.MigratedFromAnnotation] != legacy.Name {
+		t.Fatalf("new PVC should be created for the PV, %v", pvc)
+	}
+
+	reconcile("rebind released PV", true)
+	pv = getPV(t, r.Client)
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Name != name || pv.Spec.ClaimRef.UID != "" {
+		t.Fatalf("PV should be pre-bound to the new PVC, %v", pv.Spec.ClaimRef)
+	}
+	if pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimRetain {
+		t.Fatalf("PV should stay retained until bound")
+	}
+
+	// 模拟 PV 控制器完成绑定
+	pvc = getPVC(t, r.Client, minio.Namespace, name)
+	pvc.Status.Phase = corev1.ClaimBound
+	if err := r.Update(ctx, pvc); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcile("restore reclaim policy", true)
+	pv = getPV(t, r.Client)
+	if pv.Spec.PersistentVolumeReclaimPolicy != corev1.PersistentVolumeReclaimDelete {
+		t.Errorf("reclaim policy = %s, want Delete", pv.Spec.PersistentVolumeReclaimPolicy)
+	}
+	if _, ok := pv.Annotations[miniov1alpha1.ReclaimPolicyAnnotation]; ok {
+		t.Errorf("reclaim policy annotation should be removed")
+	}
+	if pvc := getPVC(t, r.Client, minio.Namespace, name); pvc.Annotations[miniov1alpha1.MigratedFromAnnotation] != "" {
+		t.Errorf("migrated-from annotation should be removed")
+	}
+}
+
+// 迁移在各步骤之间中断后重新调谐
+func TestMigrateLegacyPVCResume(t *testing.T) {
+	minio := newTestMinIO(1, 1)
+	pool := &minio.Spec.Pools[0]
+	legacyName := utils.LegacyPersistentVolumeClaimName(minio, pool, 0, 0)
+	name := utils.PersistentVolumeClaimName(minio, pool, 0, 0)
+
+	migratedPVC := func(phase corev1.PersistentVolumeClaimPhase) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   minio.Namespace,
+				Annotations: map[string]string{miniov1alpha1.MigratedFromAnnotation: legacyName},
+			},
+			Spec:   corev1.PersistentVolumeClaimSpec{VolumeName: testPVName},
+			Status: corev1.PersistentVolumeClaimStatus{Phase: phase},
+		}
+	}
+	retained := func(pv *corev1.PersistentVolume, original corev1.PersistentVolumeReclaimPolicy) *corev1.PersistentVolume {
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+		pv.Annotations = map[string]string{miniov1alpha1.ReclaimPolicyAnnotation: string(original)}
+		return pv
+	}
+
+	tests := []struct {
+		name          string
+		objs          func() []client.Object
+		wantDone      bool
+		wantLegacy    bool
+		wantPVC       bool
+		wantPolicy    corev1.PersistentVolumeReclaimPolicy
+		wantOriginal  string
+		wantClaimName string
+	}{
+		{
+			name: "PV already retained by the user",
+			objs: func() []client.Object {
+				legacy := newLegacyPVC(minio, testPVName)
+				return []client.Object{legacy, newTestPV(legacy, corev1.PersistentVolumeReclaimRetain)}
+			},
+			wantPVC:       true,
+			wantPolicy:    corev1.PersistentVolumeReclaimRetain,
+			wantClaimName: legacyName,
+		},
+		{
+			name: "interrupted after retaining the PV",
+			objs: func() []client.Object {
+				legacy := newLegacyPVC(minio, testPVName)
+				return []client.Object{legacy, retained(newTestPV(legacy, ""), corev1.PersistentVolumeReclaimDelete)}
+			},
+			wantPVC:       true,
+			wantPolicy:    corev1.PersistentVolumeReclaimRetain,
+			wantOriginal:  string(corev1.PersistentVolumeReclaimDelete),
+			wantClaimName: legacyName,
+		},
+		{
+			name: "interrupted after creating the new PVC",
+			objs: func() []client.Object {
+				legacy := newLegacyPVC(minio, testPVName)
+				return []client.Object{legacy, migratedPVC(corev1.ClaimPending), retained(newTestPV(legacy, ""), corev1.PersistentVolumeReclaimDelete)}
+			},
+			wantPVC:       true,
+			wantPolicy:    corev1.PersistentVolumeReclaimRetain,
+			wantOriginal:  string(corev1.PersistentVolumeReclaimDelete),
+			wantClaimName: legacyName,
+		},
+		{
+			name: "legacy PVC already gone",
+			objs: func() []client.Object {
+				legacy := newLegacyPVC(minio, testPVName)
+				return []client.Object{migratedPVC(corev1.ClaimPending), retained(newTestPV(legacy, ""), corev1.PersistentVolumeReclaimDelete)}
+			},
+			wantDone:      true,
+			wantPVC:       true,
+			wantPolicy:    corev1.PersistentVolumeReclaimRetain,
+			wantOriginal:  string(corev1.PersistentVolumeReclaimDelete),
+			wantClaimName: name,
+		},
+		{
+			name: "new PVC already bound",
+			objs: func() []client.Object {
+				pvc := migratedPVC(corev1.ClaimBound)
+				return []client.Object{pvc, retained(newTestPV(pvc, ""), corev1.PersistentVolumeReclaimDelete)}
+			},
+			wantDone:      true,
+			wantPVC:       true,
+			wantPolicy:    corev1.PersistentVolumeReclaimDelete,
+			wantClaimName: name,
+		},
+		{
+			name: "unbound legacy PVC",
+			objs: func() []client.Object {
+				return []client.Object{newLegacyPVC(minio, "")}
+			},
+		},
+		{
+			name: "legacy PVC terminating",
+			objs: func() []client.Object {
+				legacy := newLegacyPVC(minio, testPVName)
+				now := metav1.Now()
+				legacy.DeletionTimestamp = &now
+				legacy.Finalizers = []string{"kubernetes.io/pvc-protection"}
+				return []client.Object{legacy, newTestPV(legacy, corev1.PersistentVolumeReclaimDelete)}
+			},
+			wantLegacy:    true,
+			wantPolicy:    corev1.PersistentVolumeReclaimDelete,
+			wantClaimName: legacyName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestReconciler(t, tt.objs())
+			done, err := r.migrateLegacyPVC(context.Background(), minio, legacyName, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != tt.wantDone {
+				t.Errorf("done = %v, want %v", done, tt.wantDone)
+			}
+			if got := getPVC(t, r.Client, minio.Namespace, legacyName) != nil; got != tt.wantLegacy {
+				t.Errorf("legacy PVC exists = %v, want %v", got, tt.wantLegacy)
+			}
+			pvc := getPVC(t, r.Client, minio.Namespace, name)
+			if (pvc != nil) != tt.wantPVC {
+				t.Fatalf("new PVC exists = %v, want %v", pvc != nil, tt.wantPVC)
+			}
+			if pvc != nil && pvc.Spec.VolumeName != testPVName {
+				t.Errorf("new PVC volume = %q, want %s", pvc.Spec.VolumeName, testPVName)
+			}
+			if tt.wantPolicy == "" {
+				return
+			}
+			pv := getPV(t, r.Client)
+			if pv.Spec.PersistentVolumeReclaimPolicy != tt.wantPolicy {
+				t.Errorf("reclaim policy = %s, want %s", pv.Spec.PersistentVolumeReclaimPolicy, tt.wantPolicy)
+			}
+			if got := pv.Annotations[miniov1alpha1.ReclaimPolicyAnnotation]; got != tt.wantOriginal {
+				t.Errorf("recorded reclaim policy = %q, want %q", got, tt.wantOriginal)
+			}
+			if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Name != tt.wantClaimName {
+				t.Errorf("PV claimRef = %v, want %s", pv.Spec.ClaimRef, tt.wantClaimName)
+			}
+		})
+	}
+}
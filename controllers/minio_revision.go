@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 为当前规格记录 ControllerRevision 并清理超出数量限制的历史版本，返回当前规格对应的版本名称
+func (r *MinIOReconciler) syncControllerRevisions(ctx context.Context, minio *miniov1alpha1.MinIO) (string, error) {
+	// 清理旧版本中与 MinIO 实例同名的 ControllerRevision
+	exist, legacyCr, err := utils.ExistControllerRevision(minio.Name, minio.Namespace, r.Client)
+	if err != nil {
+		return "", err
+	}
+	if exist {
+		if err := utils.DeleteControllerRevision(legacyCr, r.Client); err != nil {
+			return "", err
+		}
+	}
+
+	revisions, err := utils.ListControllerRevisions(ctx, r.Client, minio)
+	if err != nil {
+		return "", err
+	}
+	var maxRevision int64
+	if len(revisions) > 0 {
+		maxRevision = revisions[len(revisions)-1].Revision
+	}
+
+	newCr := minio.NewControllerRevision(maxRevision + 1)
+	found := false
+	for i := range revisions {
+		cr := &revisions[i]
+		if cr.Name != newCr.Name {
+			continue
+		}
+		found = true
+		// 与历史版本的规格相同（如回滚），将其版本号更新为最新
+		if cr.Revision != maxRevision {
+			cr.Revision = newCr.Revision
+			cr.Labels[miniov1alpha1.Revision] = newCr.Labels[miniov1alpha1.Revision]
+			if err := r.Update(ctx, cr); err != nil {
+				klog.Errorf("update ControllerRevision %s/%s error, %s", cr.Namespace, cr.Name, err)
+				return "", err
+			}
+		}
+	}
+	if !found {
+		if err := utils.CreateControllerRevision(minio, newCr, r.Client, r.Scheme); err != nil {
+			return "", err
+		}
+		revisions = append(revisions, *newCr)
+	}
+
+	// 清理超出数量限制的历史版本，正在使用的版本不会被清理
+	history := len(revisions) - 1
+	for i := range revisions {
+		if history <= minio.RevisionHistoryLimit() {
+			break
+		}
+		cr := &revisions[i]
+		if cr.Name == newCr.Name || cr.Name == minio.Status.CurrentRevision {
+			continue
+		}
+		if err := utils.DeleteControllerRevision(cr, r.Client); err != nil {
+			return "", err
+		}
+		history--
+	}
+
+	return newCr.Name, nil
+}
+
+// 将 MinIO 实例的规格回滚到指定的历史版本
+func (r *MinIOReconciler) rollback(ctx context.Context, minio *miniov1alpha1.MinIO, revision string) error {
+	revisions, err := utils.ListControllerRevisions(ctx, r.Client, minio)
+	if err != nil {
+		return err
+	}
+
+	target, err := strconv.ParseInt(revision, 10, 64)
+	if err == nil && target == 0 {
+		// 回滚到当前版本的上一个版本
+		target = -1
+		for i := range revisions {
+			if revisions[i].Name == minio.Status.UpdateRevision && i > 0 {
+				target = revisions[i-1].Revision
+			}
+		}
+	}
+
+	var spec *miniov1alpha1.MinIOSpec
+	for i := range revisions {
+		if err == nil && revisions[i].Revision == target {
+			spec = &miniov1alpha1.MinIOSpec{}
+			if err := json.Unmarshal(revisions[i].Data.Raw, spec); err != nil {
+				klog.Errorf("unmarshal ControllerRevision %s/%s error, %s", revisions[i].Namespace, revisions[i].Name, err)
+				return err
+			}
+		}
+	}
+
+	delete(minio.Annotations, miniov1alpha1.RollbackToAnnotation)
+	if spec == nil {
+		r.Recorder.Eventf(minio, corev1.EventTypeWarning, "RollbackFailed", "Revision %s of MinIO not found", revision)
+		return r.Update(ctx, minio)
+	}
+
+	spec.RevisionHistoryLimit = minio.Spec.RevisionHistoryLimit
+	minio.Spec = *spec
+	if err := r.Update(ctx, minio); err != nil {
+		return err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "RolledBack", "MinIO rolled back to revision %d", target)
+
+	return nil
+}
+
+// 查询指定 ControllerRevision 中记录的规格，版本不存在时返回 nil
+func revisionSpec(ctx context.Context, c client.Client, minio *miniov1alpha1.MinIO, name string) (*miniov1alpha1.MinIOSpec, error) {
+	if name == "" {
+		return nil, nil
+	}
+	revisions, err := utils.ListControllerRevisions(ctx, c, minio)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Name != name {
+			continue
+		}
+		spec := &miniov1alpha1.MinIOSpec{}
+		if err := json.Unmarshal(revisions[i].Data.Raw, spec); err != nil {
+			klog.Errorf("unmarshal ControllerRevision %s/%s error, %s", revisions[i].Namespace, revisions[i].Name, err)
+			return nil, err
+		}
+		return spec, nil
+	}
+	return nil, nil
+}
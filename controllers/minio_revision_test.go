@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 依次以 images 中的镜像记录 ControllerRevision，返回每次记录的版本名称
+func syncRevisions(t *testing.T, r *MinIOReconciler, minio *miniov1alpha1.MinIO, images ...string) []string {
+	var names []string
+	for _, image := range images {
+		minio.Spec.Image = image
+		name, err := r.syncControllerRevisions(context.TODO(), minio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		minio.Status.UpdateRevision = name
+		names = append(names, name)
+	}
+	return names
+}
+
+// 返回 ControllerRevision 的版本号，以镜像标识规格
+func revisionImages(t *testing.T, r *MinIOReconciler, minio *miniov1alpha1.MinIO) map[string]int64 {
+	revisions, err := utils.ListControllerRevisions(context.TODO(), r.Client, minio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := map[string]int64{}
+	for i := range revisions {
+		spec, err := revisionSpec(context.TODO(), r.Client, minio, revisions[i].Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		images[spec.Image] = revisions[i].Revision
+	}
+	return images
+}
+
+func TestSyncControllerRevisions(t *testing.T) {
+	t.Run("same spec keeps a single revision", func(t *testing.T) {
+		minio := newTestMinIO(4, 1)
+		r := newTestReconciler(t, nil)
+		names := syncRevisions(t, r, minio, "minio/minio:1", "minio/minio:1")
+		if names[0] != names[1] {
+			t.Errorf("revision names = %v, want the same name for the same spec", names)
+		}
+		if got := revisionImages(t, r, minio); len(got) != 1 || got["minio/minio:1"] != 1 {
+			t.Errorf("revisions = %v, want minio/minio:1 at revision 1", got)
+		}
+	})
+
+	t.Run("history limit prunes the oldest revisions", func(t *testing.T) {
+		minio := newTestMinIO(4, 1)
+		limit := int32(2)
+		minio.Spec.RevisionHistoryLimit = &limit
+		r := newTestReconciler(t, nil)
+		syncRevisions(t, r, minio, "minio/minio:1", "minio/minio:2", "minio/minio:3", "minio/minio:4", "minio/minio:5")
+		want := map[string]int64{"minio/minio:3": 3, "minio/minio:4": 4, "minio/minio:5": 5}
+		if got := revisionImages(t, r, minio); !equalRevisions(got, want) {
+			t.Errorf("revisions = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("deployed revision is never pruned", func(t *testing.T) {
+		minio := newTestMinIO(4, 1)
+		limit := int32(0)
+		minio.Spec.RevisionHistoryLimit = &limit
+		r := newTestReconciler(t, nil)
+		names := syncRevisions(t, r, minio, "minio/minio:1")
+		minio.Status.CurrentRevision = names[0]
+		syncRevisions(t, r, minio, "minio/minio:2", "minio/minio:3")
+		want := map[string]int64{"minio/minio:1": 1, "minio/minio:3": 3}
+		if got := revisionImages(t, r, minio); !equalRevisions(got, want) {
+			t.Errorf("revisions = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("returning to an old spec reuses its revision", func(t *testing.T) {
+		minio := newTestMinIO(4, 1)
+		r := newTestReconciler(t, nil)
+		names := syncRevisions(t, r, minio, "minio/minio:1", "minio/minio:2", "minio/minio:1")
+		if names[0] != names[2] {
+			t.Errorf("revision names = %v, want the first revision to be reused", names)
+		}
+		want := map[string]int64{"minio/minio:1": 3, "minio/minio:2": 2}
+		if got := revisionImages(t, r, minio); !equalRevisions(got, want) {
+			t.Errorf("revisions = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("legacy revision named after the instance is removed", func(t *testing.T) {
+		minio := newTestMinIO(4, 1)
+		legacy := minio.NewControllerRevision(1)
+		legacy.Name = minio.Name
+		r := newTestReconciler(t, []client.Object{legacy})
+		syncRevisions(t, r, minio, "minio/minio:1")
+		if exist, _, err := utils.ExistControllerRevision(minio.Name, minio.Namespace, r.Client); err != nil || exist {
+			t.Errorf("legacy ControllerRevision exists = %v, %v, want deleted", exist, err)
+		}
+	})
+}
+
+func equalRevisions(got, want map[string]int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		revision  string
+		wantImage string
+		wantEvent string
+	}{
+		{name: "zero rolls back to the previous revision", revision: "0", wantImage: "minio/minio:2", wantEvent: "RolledBack"},
+		{name: "explicit revision", revision: "1", wantImage: "minio/minio:1", wantEvent: "RolledBack"},
+		{name: "unknown revision", revision: "9", wantImage: "minio/minio:3", wantEvent: "RollbackFailed"},
+		{name: "invalid revision", revision: "latest", wantImage: "minio/minio:3", wantEvent: "RollbackFailed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO(4, 1)
+			limit := int32(5)
+			minio.Spec.RevisionHistoryLimit = &limit
+			r := newTestReconciler(t, []client.Object{minio})
+			syncRevisions(t, r, minio, "minio/minio:1", "minio/minio:2", "minio/minio:3")
+
+			var current miniov1alpha1.MinIO
+			if err := r.Get(context.TODO(), types.NamespacedName{Namespace: minio.Namespace, Name: minio.Name}, &current); err != nil {
+				t.Fatal(err)
+			}
+			current.Spec.Image = "minio/minio:3"
+			current.Status.UpdateRevision = minio.Status.UpdateRevision
+			current.Annotations = map[string]string{miniov1alpha1.RollbackToAnnotation: tt.revision}
+			if err := r.rollback(context.TODO(), &current, tt.revision); err != nil {
+				t.Fatal(err)
+			}
+
+			var got miniov1alpha1.MinIO
+			if err := r.Get(context.TODO(), types.NamespacedName{Namespace: minio.Namespace, Name: minio.Name}, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Spec.Image != tt.wantImage {
+				t.Errorf("image = %s, want %s", got.Spec.Image, tt.wantImage)
+			}
+			if _, ok := got.Annotations[miniov1alpha1.RollbackToAnnotation]; ok {
+				t.Errorf("rollback annotation was not removed")
+			}
+			if got.Spec.RevisionHistoryLimit == nil || *got.Spec.RevisionHistoryLimit != limit {
+				t.Errorf("revisionHistoryLimit = %v, want %d", got.Spec.RevisionHistoryLimit, limit)
+			}
+			events := recordedEvents(r)
+			if len(events) != 1 || !strings.Contains(events[0], tt.wantEvent) {
+				t.Errorf("events = %v, want %s", events, tt.wantEvent)
+			}
+		})
+	}
+}
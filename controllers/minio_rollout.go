@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 分批推进服务池的滚动更新，返回 true 表示更新已完成
+// 通过 StatefulSet 的 partition 控制更新范围，由 StatefulSet 逐个删除并重建 Pod，
+// 只有已更新的 Pod 全部就绪且 MinIO 集群健康时才会推进下一批，每批最多 maxUnavailable 个 Pod，
+// 未更新且未就绪的 Pod 已不可用，更新它们不占用 maxUnavailable，也不需要检查集群健康
+func (r *MinIOReconciler) rolloutStatefulSet(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) (bool, error) {
+	ss, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Get(ctx, minio.PoolStatefulSetName(pool), metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	// StatefulSet 控制器还未处理最新的变更
+	if ss.Status.ObservedGeneration < ss.Generation {
+		return false, nil
+	}
+
+	replicas := *ss.Spec.Replicas
+	partition := utils.StatefulSetPartition(ss)
+	if partition == 0 {
+		return ss.Status.UpdatedReplicas == replicas && ss.Status.ReadyReplicas == replicas, nil
+	}
+
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPoolLabels(pool))); err != nil {
+		klog.Errorf("query Pod list of pool %s error, %s", pool.Name, err)
+		return false, err
+	}
+	pods := make(map[string]*corev1.Pod, len(podList.Items))
+	for i := range podList.Items {
+		pods[podList.Items[i].Name] = &podList.Items[i]
+	}
+
+	// broken 记录未更新且未就绪的 Pod
+	unavailable := 0
+	broken := make([]bool, replicas)
+	for i := 0; i < int(replicas); i++ {
+		pod, ok := pods[minio.PoolPodName(pool, i)]
+		ready := ok && utils.IsPodReady(pod)
+		updated := ok && pod.Labels[appsv1.ControllerRevisionHashLabelKey] == ss.Status.UpdateRevision
+
+		// 上一批更新的 Pod 必须已是最新版本并就绪
+		if int32(i) >= partition {
+			if !ready || !updated {
+				return false, nil
+			}
+			continue
+		}
+		if !ready {
+			if updated {
+				unavailable++
+			} else {
+				broken[i] = true
+			}
+		}
+	}
+
+	// 从序号最大的 Pod 开始，已不可用的 Pod 直接更新，就绪的 Pod 最多更新 maxUnavailable 个
+	budget := minio.PoolMaxUnavailable(pool) - unavailable
+	next, replacing := partition, 0
+	for next > 0 {
+		if !broken[next-1] {
+			if replacing >= budget {
+				break
+			}
+			replacing++
+		}
+		next--
+	}
+	if next == partition {
+		return false, nil
+	}
+
+	// 更新就绪的 Pod 前需要确认集群健康，否则只更新序号最大的几个已不可用的 Pod
+	if replacing > 0 {
+		if healthy, err := r.minioHealthy(ctx, minio); !healthy {
+			klog.Infof("MinIO %s/%s is not healthy, pause rolling update of ready Pods in pool %s, %v", minio.Namespace, minio.Name, pool.Name, err)
+			next = partition
+			for next > 0 && broken[next-1] {
+				next--
+			}
+			if next == partition {
+				return false, nil
+			}
+		}
+	}
+
+	ss.Spec.UpdateStrategy.RollingUpdate.Partition = &next
+	if _, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "PoolRollingUpdate", "Rolling update pool %s, updating Pods %d to %d", pool.Name, next, partition-1)
+
+	return false, nil
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRolloutStatefulSet(t *testing.T) {
+	const (
+		oldRevision = "minio-ss-pool-0-old"
+		newRevision = "minio-ss-pool-0-new"
+	)
+	type pod struct {
+		updated bool
+		ready   bool
+		missing bool
+	}
+	ready := pod{ready: true}
+	broken := pod{}
+	updated := pod{updated: true, ready: true}
+
+	tests := []struct {
+		name           string
+		partition      int32
+		maxUnavailable *intstr.IntOrString
+		pods           []pod
+		healthy        bool
+		stale          bool
+		wantPartition  int32
+		wantDone       bool
+		wantHealthRuns int
+	}{
+		{
+			name:           "first batch",
+			partition:      4,
+			pods:           []pod{ready, ready, ready, ready},
+			healthy:        true,
+			wantPartition:  3,
+			wantHealthRuns: 1,
+		},
+		{
+			name:           "batch capped by maxUnavailable",
+			partition:      4,
+			maxUnavailable: intstrPtr(intstr.FromInt(2)),
+			pods:           []pod{ready, ready, ready, ready},
+			healthy:        true,
+			wantPartition:  2,
+			wantHealthRuns: 1,
+		},
+		{
+			name:           "percent maxUnavailable",
+			partition:      4,
+			maxUnavailable: intstrPtr(intstr.FromString("50%")),
+			pods:           []pod{ready, ready, ready, ready},
+			healthy:        true,
+			wantPartition:  2,
+			wantHealthRuns: 1,
+		},
+		{
+			name:           "broken pods do not use the budget",
+			partition:      4,
+			pods:           []pod{ready, ready, broken, ready},
+			healthy:        true,
+			wantPartition:  2,
+			wantHealthRuns: 1,
+		},
+		{
+			name:           "missing pod is replaced without using the budget",
+			partition:      4,
+			pods:           []pod{ready, ready, ready, {missing: true}},
+			healthy:        true,
+			wantPartition:  2,
+			wantHealthRuns: 1,
+		},
+		{
+			name:           "broken pods are replaced without health check",
+			partition:      4,
+			maxUnavailable: intstrPtr(intstr.FromInt(1)),
+			pods:           []pod{broken, broken, broken, broken},
+			wantPartition:  0,
+			wantHealthRuns: 0,
+		},
+		{
+			name:           "unhealthy cluster only replaces broken pods at the top",
+			partition:      4,
+			pods:           []pod{ready, ready, broken, broken},
+			healthy:        false,
+			wantPartition:  2,
+			wantHealthRuns: 1,
+		},
+		{
+			name:           "unhealthy cluster pauses ready pods",
+			partition:      4,
+			pods:           []pod{ready, broken, ready, ready},
+			healthy:        false,
+			wantPartition:  4,
+			wantHealthRuns: 1,
+		},
+		{
+			name:           "updated pods below the partition use the budget",
+			partition:      3,
+			pods:           []pod{ready, {updated: true}, ready, updated},
+			healthy:        true,
+			wantPartition:  3,
+			wantHealthRuns: 0,
+		},
+		{
+			name:          "waits for updated pods to be ready",
+			partition:     3,
+			pods:          []pod{ready, ready, ready, {updated: true}},
+			healthy:       true,
+			wantPartition: 3,
+		},
+		{
+			name:          "waits for updated pods to be recreated",
+			partition:     3,
+			pods:          []pod{ready, ready, ready, ready},
+			healthy:       true,
+			wantPartition: 3,
+		},
+		{
+			name:          "waits for the StatefulSet controller",
+			partition:     4,
+			pods:          []pod{ready, ready, ready, ready},
+			healthy:       true,
+			stale:         true,
+			wantPartition: 4,
+		},
+		{
+			name:           "last batch reaches partition 0",
+			partition:      1,
+			pods:           []pod{ready, updated, updated, updated},
+			healthy:        true,
+			wantPartition:  0,
+			wantHealthRuns: 1,
+		},
+		{
+			name:          "rollout done",
+			partition:     0,
+			pods:          []pod{updated, updated, updated, updated},
+			wantPartition: 0,
+			wantDone:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO(len(tt.pods), 1)
+			minio.Spec.MaxUnavailable = tt.maxUnavailable
+			pool := &minio.Spec.Pools[0]
+
+			ss := utils.NewStatefulSetForMinIOPool(minio, pool, nil)
+			partition := tt.partition
+			ss.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+			ss.Generation = 2
+			ss.Status.ObservedGeneration = 2
+			if tt.stale {
+				ss.Status.ObservedGeneration = 1
+			}
+			ss.Status.UpdateRevision = newRevision
+			var objs []client.Object
+			for i, p := range tt.pods {
+				if p.missing {
+					continue
+				}
+				revision := oldRevision
+				if p.updated {
+					revision = newRevision
+				}
+				objs = append(objs, newTestPod(minio, pool, i, revision, p.ready))
+			}
+			if tt.partition == 0 {
+				ss.Status.UpdatedReplicas = int32(len(tt.pods))
+				ss.Status.ReadyReplicas = int32(len(tt.pods))
+			}
+
+			r := newTestReconciler(t, objs, ss)
+			healthRuns := 0
+			r.healthCheck = func(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+				healthRuns++
+				return tt.healthy, nil
+			}
+
+			done, err := r.rolloutStatefulSet(context.Background(), minio, pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done != tt.wantDone {
+				t.Errorf("done = %v, want %v", done, tt.wantDone)
+			}
+			if healthRuns != tt.wantHealthRuns {
+				t.Errorf("health checks = %d, want %d", healthRuns, tt.wantHealthRuns)
+			}
+			found, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Get(context.Background(), ss.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := utils.StatefulSetPartition(found); got != tt.wantPartition {
+				t.Errorf("partition = %d, want %d", got, tt.wantPartition)
+			}
+			if events := recordedEvents(r); (len(events) > 0) != (tt.wantPartition != tt.partition) {
+				t.Errorf("unexpected events %v", events)
+			}
+		})
+	}
+}
+
+func intstrPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"time"
 
 	"k8s.io/klog/v2"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	corev1 "k8s.io/api/core/v1"
 
@@ -19,23 +22,33 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+// PVC 处于 Pending 状态超过该时间后视为无法绑定
+const pvcBindTimeout = 5 * time.Minute
+
 // MinIOStatusReconciler reconciles a MinIO Status object
 type MinIOStatusReconciler struct {
 	client.Client
-	KubeClient *kubernetes.Clientset
+	KubeClient kubernetes.Interface
 	Scheme     *runtime.Scheme
 
 	Recorder record.EventRecorder
+
+	// 同时上报状态的 MinIO 实例数量，由 --status-concurrency 设置
+	MaxConcurrentReconciles int
+
+	statusWriter *statusWriter
 }
 
 func (r *MinIOStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var minio miniov1alpha1.MinIO
 	if err := r.Get(ctx, req.NamespacedName, &minio); err != nil {
-		if errors.IsNotFound(err) {
-			return ctrl.Result{}, err
-		}
+		// MinIO 实例已删除
+		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	// 设置 PVC 状态
@@ -58,6 +71,18 @@ func (r *MinIOStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		if pvc.Spec.StorageClassName != nil {
 			ps.StorageClass = *pvc.Spec.StorageClassName
 		}
+		switch pvc.Status.Phase {
+		case corev1.ClaimPending:
+			if time.Since(pvc.CreationTimestamp.Time) > pvcBindTimeout {
+				ps.Message = fmt.Sprintf("PVC can not be bound for more than %s", pvcBindTimeout)
+			}
+		case corev1.ClaimLost:
+			ps.Message = fmt.Sprintf("PersistentVolume %s is lost", pvc.Spec.VolumeName)
+		case corev1.ClaimBound:
+			if err := r.setPVCResizeStatus(ctx, &minio, &pvc, &ps); err != nil {
+				return ctrl.Result{Requeue: true}, err
+			}
+		}
 		pvcStatus = append(pvcStatus, ps)
 	}
 	minio.Status.PVCStatus = pvcStatus
@@ -73,11 +98,13 @@ func (r *MinIOStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		if svc.Name == minio.MinIOCIServiceName() {
 			switch svc.Spec.Type {
 			case corev1.ServiceTypeNodePort:
-				svcStatus.MinIO = fmt.Sprintf("http://%s:%s",
+				svcStatus.MinIO = fmt.Sprintf("%s://%s:%s",
+					minio.Scheme(),
 					r.nodeIP(),
 					fmt.Sprint(svc.Spec.Ports[0].NodePort))
 			case corev1.ServiceTypeClusterIP:
-				svcStatus.MinIO = fmt.Sprintf("http://%s.%s.svc.%s:%s",
+				svcStatus.MinIO = fmt.Sprintf("%s://%s.%s.svc.%s:%s",
+					minio.Scheme(),
 					svc.Name,
 					svc.Namespace,
 					miniov1alpha1.GetClusterDomain(),
@@ -87,11 +114,13 @@ func (r *MinIOStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		if svc.Name == minio.MinIOConsoleServiceName() {
 			switch svc.Spec.Type {
 			case corev1.ServiceTypeNodePort:
-				svcStatus.Console = fmt.Sprintf("http://%s:%s",
+				svcStatus.Console = fmt.Sprintf("%s://%s:%s",
+					minio.Scheme(),
 					r.nodeIP(),
 					fmt.Sprint(svc.Spec.Ports[0].NodePort))
 			case corev1.ServiceTypeClusterIP:
-				svcStatus.Console = fmt.Sprintf("http://%s.%s.svc.%s:%s",
+				svcStatus.Console = fmt.Sprintf("%s://%s.%s.svc.%s:%s",
+					minio.Scheme(),
 					svc.Name,
 					svc.Namespace,
 					miniov1alpha1.GetClusterDomain(),
@@ -99,11 +128,15 @@ func (r *MinIOStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			}
 		}
 	}
-	minio.Status.Service = svcStatus
+	minio.Status.Service = &svcStatus
 
-	// 设置 Pool 状态
+	// 设置 Pool 状态，包括正在下线的服务池
+	pools, err := effectivePools(ctx, r.Client, r.KubeClient, &minio)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, err
+	}
 	var poolStatus []miniov1alpha1.PoolStatus
-	for _, pool := range minio.Spec.Pools {
+	for _, pool := range pools {
 
 		listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s,%s=%s",
 			miniov1alpha1.MinIOLable, minio.Name, miniov1alpha1.PoolLabel, pool.Name),
@@ -141,52 +174,137 @@ func (r *MinIOStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			Replicas:          pool.Servers,
 			Servers:           servers,
 		}
+
+		// 设置滚动更新进度
+		ss, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Get(ctx, minio.PoolStatefulSetName(&pool), metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("query StatefulSet error, %s", err)
+			return ctrl.Result{Requeue: true}, err
+		}
+		if err == nil {
+			ps.UpdatedReplicas = int(ss.Status.UpdatedReplicas)
+			ps.CurrentRevision = ss.Status.CurrentRevision
+			ps.UpdateRevision = ss.Status.UpdateRevision
+			ps.Partition = int(utils.StatefulSetPartition(ss))
+			if ps.Status != miniov1alpha1.PoolStatusFailed && ss.Status.UpdateRevision != ss.Status.CurrentRevision {
+				ps.Status = miniov1alpha1.PoolStatusUpdating
+			}
+		}
 		poolStatus = append(poolStatus, ps)
 	}
 	minio.Status.PoolStatus = poolStatus
 
-	// 设置部署状态
-	deployStatus := miniov1alpha1.DeployStatusCompleted
+	// 所有服务池的 Pod 都已运行时可用
+	available := metav1.ConditionTrue
+	reason, message := "PoolsAvailable", "All pools are available"
 	for _, ps := range minio.Status.PoolStatus {
 		if ps.Status == miniov1alpha1.PoolStatusFailed {
-			deployStatus = miniov1alpha1.DeployStatusFailed
-		} else if ps.Status == miniov1alpha1.PoolStatusRunning {
-			deployStatus = miniov1alpha1.DeployStatusRunning
+			available = metav1.ConditionFalse
+			reason, message = "PoolFailed", fmt.Sprintf("Pods of pool %s failed", ps.Name)
+			break
+		}
+		if ps.Status != miniov1alpha1.PoolStatusCompleted || ps.AvailableReplicas < ps.Replicas {
+			available = metav1.ConditionFalse
+			reason, message = "PoolsUnavailable", fmt.Sprintf("%d/%d servers of pool %s are running", ps.AvailableReplicas, ps.Replicas, ps.Name)
 		}
 	}
-	minio.Status.Status = deployStatus
+	setCondition(&minio, miniov1alpha1.ConditionAvailable, available, reason, message)
 
-	if err := r.updateMinIOStatus(ctx, &minio); err != nil {
+	if err := r.statusWriter.Apply(ctx, &minio); err != nil {
 		return ctrl.Result{Requeue: true}, err
 	}
 
-	if minio.Status.Status != miniov1alpha1.DeployStatusCompleted {
+	if available != metav1.ConditionTrue {
 		return ctrl.Result{Requeue: true}, nil
 	}
 
 	return ctrl.Result{}, nil
 }
 
-func (r *MinIOStatusReconciler) updateMinIOStatus(ctx context.Context, minio *miniov1alpha1.MinIO) error {
-	// if minio.Status.Status == miniov1alpha1.DeployStatusCompleted && minio.Status.AvailableReplicas == minio.Spec.Servers {
-	// 	return nil
-	// }
-
-	minioCopy := minio.DeepCopy()
-	minioCopy.Spec = miniov1alpha1.MinIOSpec{}
-	minioCopy.Status = minio.Status
-
-	if err := r.Status().Update(ctx, minioCopy); err != nil {
-		if errors.IsConflict(err) {
-			klog.Infof("Hit conflict issue, getting latest version of MinIO %s", minio.Name)
-			err = r.Get(ctx, client.ObjectKeyFromObject(minio), minioCopy)
-			if err != nil {
-				return err
-			}
-			return r.updateMinIOStatus(ctx, minioCopy)
+// MinIOStatusReconciler 负责的状态字段
+func statusControllerFields(status *miniov1alpha1.MinIOStatus) miniov1alpha1.MinIOStatus {
+	fields := miniov1alpha1.MinIOStatus{
+		Service:    status.Service,
+		PVCStatus:  status.PVCStatus,
+		Conditions: filterConditions(status.Conditions, miniov1alpha1.ConditionAvailable),
+	}
+	// 下线进度由 MinIOReconciler 维护，服务及卷的健康状态由 MinIOHealthCheckerReconciler 维护
+	for _, ps := range status.PoolStatus {
+		ps.Decommission = nil
+		servers := make([]miniov1alpha1.MinIOServer, 0, len(ps.Servers))
+		for _, s := range ps.Servers {
+			servers = append(servers, miniov1alpha1.MinIOServer{
+				Name:   s.Name,
+				HostIP: s.HostIP,
+				PodIP:  s.PodIP,
+				Status: s.Status,
+			})
 		}
+		ps.Servers = servers
+		fields.PoolStatus = append(fields.PoolStatus, ps)
+	}
+	return fields
+}
+
+// 设置 PVC 的扩容进度
+func (r *MinIOStatusReconciler) setPVCResizeStatus(ctx context.Context, minio *miniov1alpha1.MinIO, pvc *corev1.PersistentVolumeClaim, ps *miniov1alpha1.PVCStatus) error {
+	requested := pvc.Spec.Resources.Requests.Storage()
+	ps.RequestedCapacity = requested.String()
+
+	for _, cond := range pvc.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case corev1.PersistentVolumeClaimResizing:
+			ps.ResizeStatus = miniov1alpha1.PVCResizing
+			ps.Message = cond.Message
+		case corev1.PersistentVolumeClaimFileSystemResizePending:
+			ps.ResizeStatus = miniov1alpha1.PVCFileSystemResizePending
+			ps.Message = cond.Message
+		}
+	}
+	if pvc.Status.ResizeStatus != nil {
+		switch *pvc.Status.ResizeStatus {
+		case corev1.PersistentVolumeClaimControllerExpansionFailed, corev1.PersistentVolumeClaimNodeExpansionFailed:
+			ps.ResizeStatus = miniov1alpha1.PVCResizeFailed
+			ps.Message = fmt.Sprintf("volume expansion failed, %s", *pvc.Status.ResizeStatus)
+		}
+	}
+	if ps.ResizeStatus != "" {
+		return nil
+	}
+
+	// 服务池模板请求的容量大于 PVC 时说明扩容还未开始
+	var desired *resource.Quantity
+	for _, pool := range minio.Spec.Pools {
+		if pool.Name == pvc.Labels[miniov1alpha1.PoolLabel] && pool.VolumeClaimTemplate != nil {
+			desired = pool.VolumeClaimTemplate.Spec.Resources.Requests.Storage()
+		}
+	}
+	if desired == nil || desired.Cmp(*requested) <= 0 {
+		return nil
+	}
+	ps.ResizeStatus = miniov1alpha1.PVCResizePending
+	ps.Message = fmt.Sprintf("waiting to be expanded to %s", desired)
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		ps.ResizeStatus = miniov1alpha1.PVCResizeFailed
+		ps.Message = "PVC has no StorageClass, can not be expanded"
+		return nil
+	}
+	sc, err := r.KubeClient.StorageV1().StorageClasses().Get(ctx, *pvc.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		klog.Errorf("get StorageClass %s error, %s", *pvc.Spec.StorageClassName, err)
 		return err
 	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		ps.ResizeStatus = miniov1alpha1.PVCResizeFailed
+		ps.Message = fmt.Sprintf("StorageClass %s does not allow volume expansion", sc.Name)
+	}
+
 	return nil
 }
 
@@ -208,11 +326,13 @@ func (r *MinIOStatusReconciler) nodeIP() string {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MinIOStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.statusWriter = newStatusWriter(mgr.GetClient(), statusFieldManager, statusControllerFields)
 	return ctrl.NewControllerManagedBy(mgr).
+		Named("minio-status").
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		For(&miniov1alpha1.MinIO{}).
-		For(&corev1.Pod{}).
-		For(&corev1.Service{}).
-		// For(&corev1.PersistentVolumeClaim{}).
-		// For(&corev1.Secret{}).
+		Owns(&corev1.Service{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(minioForObject)).
+		Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, handler.EnqueueRequestsFromMapFunc(minioForObject)).
 		Complete(r)
 }
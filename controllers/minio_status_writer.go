@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 各 controller 提交状态时使用的 field manager，互不覆盖对方负责的字段
+const (
+	minioFieldManager         = "minio-controller"
+	statusFieldManager        = "minio-status-controller"
+	healthCheckerFieldManager = "minio-health-checker"
+)
+
+// 提交状态失败时的重试策略，最多重试 5 次
+var statusBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 100 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
+// 以 server-side apply 方式提交 MinIO 状态中由某个 controller 负责的字段
+type statusWriter struct {
+	client client.Client
+	// 提交时使用的 field manager
+	fieldManager string
+	// 从 MinIO 状态中取出该 field manager 负责的字段
+	fields func(status *miniov1alpha1.MinIOStatus) miniov1alpha1.MinIOStatus
+}
+
+func newStatusWriter(c client.Client, fieldManager string, fields func(status *miniov1alpha1.MinIOStatus) miniov1alpha1.MinIOStatus) *statusWriter {
+	return &statusWriter{client: c, fieldManager: fieldManager, fields: fields}
+}
+
+// 提交 minio 中负责的状态字段，与当前状态相同时不提交，本次未包含的字段不再由该 field manager 维护
+// 字段已由其他 field manager 设置时先记录冲突，再强制接管这些字段
+func (w *statusWriter) Apply(ctx context.Context, minio *miniov1alpha1.MinIO) error {
+	status := w.fields(&minio.Status)
+
+	var current miniov1alpha1.MinIO
+	if err := w.client.Get(ctx, client.ObjectKeyFromObject(minio), &current); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if equality.Semantic.DeepEqual(w.fields(&current.Status), status) {
+		return nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+	// 只提交 status，避免 spec 中的默认值被记录到 field manager 名下
+	patch := &unstructured.Unstructured{}
+	patch.SetGroupVersionKind(miniov1alpha1.GroupVersion.WithKind(miniov1alpha1.MinIOCRDResourceKind))
+	patch.SetNamespace(minio.Namespace)
+	patch.SetName(minio.Name)
+	patch.Object["status"] = content
+
+	force := false
+	err = retry.OnError(statusBackoff, isRetriableStatusError, func() error {
+		opts := []client.PatchOption{client.FieldOwner(w.fieldManager)}
+		if force {
+			opts = append(opts, client.ForceOwnership)
+		}
+		err := w.client.Status().Patch(ctx, patch, client.Apply, opts...)
+		if errors.IsConflict(err) {
+			statusConflicts.WithLabelValues(w.fieldManager).Inc()
+			klog.Warningf("apply status of MinIO %s/%s as %s conflicts with other field managers, %s", minio.Namespace, minio.Name, w.fieldManager, err)
+			force = true
+		}
+		return err
+	})
+	if err != nil {
+		klog.Errorf("apply status of MinIO %s/%s as %s error, %s", minio.Namespace, minio.Name, w.fieldManager, err)
+		return err
+	}
+	return nil
+}
+
+// 冲突、服务端超时及限流时重试
+func isRetriableStatusError(err error) bool {
+	return errors.IsConflict(err) || errors.IsServerTimeout(err) || errors.IsTimeout(err) || errors.IsTooManyRequests(err)
+}
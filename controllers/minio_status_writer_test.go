@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// 记录状态提交次数及最近一次提交内容的 client
+type patchCountingClient struct {
+	client.Client
+	patches int
+	applied *miniov1alpha1.MinIO
+}
+
+func (c *patchCountingClient) Status() client.StatusWriter {
+	return &patchCountingStatusWriter{StatusWriter: c.Client.Status(), c: c}
+}
+
+type patchCountingStatusWriter struct {
+	client.StatusWriter
+	c *patchCountingClient
+}
+
+func (w *patchCountingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.c.patches++
+	applied := &miniov1alpha1.MinIO{}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, applied); err != nil {
+			return err
+		}
+		w.c.applied = applied
+	}
+	return nil
+}
+
+func newFakeStatusClient(t *testing.T, objs ...client.Object) *patchCountingClient {
+	scheme := runtime.NewScheme()
+	if err := miniov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &patchCountingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()}
+}
+
+func TestStatusWriterSkipsUnchangedStatus(t *testing.T) {
+	minio := &miniov1alpha1.MinIO{
+		ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "default"},
+		Status: miniov1alpha1.MinIOStatus{
+			Conditions: []metav1.Condition{
+				{Type: miniov1alpha1.ConditionHealthy, Status: metav1.ConditionTrue, Reason: "HealthCheckPassed"},
+			},
+		},
+	}
+	c := newFakeStatusClient(t, minio)
+	w := newStatusWriter(c, healthCheckerFieldManager, healthCheckerFields)
+
+	// 其他 field manager 负责的字段变化不会触发提交
+	local := minio.DeepCopy()
+	local.Status.ObservedGeneration = 2
+	setCondition(local, miniov1alpha1.ConditionAvailable, metav1.ConditionTrue, "AllPodsReady", "")
+	if err := w.Apply(context.Background(), local); err != nil {
+		t.Fatal(err)
+	}
+	if c.patches != 0 {
+		t.Errorf("expected no patch for unchanged fields, got %d", c.patches)
+	}
+
+	setCondition(local, miniov1alpha1.ConditionHealthy, metav1.ConditionFalse, "HealthCheckFailed", "")
+	if err := w.Apply(context.Background(), local); err != nil {
+		t.Fatal(err)
+	}
+	if c.patches != 1 {
+		t.Errorf("expected 1 patch for changed fields, got %d", c.patches)
+	}
+}
+
+func TestStatusWriterIgnoresDeletedMinIO(t *testing.T) {
+	c := newFakeStatusClient(t)
+	w := newStatusWriter(c, minioFieldManager, minioStatusFields)
+
+	minio := &miniov1alpha1.MinIO{ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "default"}}
+	if err := w.Apply(context.Background(), minio); err != nil {
+		t.Fatalf("expected no error for deleted MinIO, got %s", err)
+	}
+	if c.patches != 0 {
+		t.Errorf("expected no patch for deleted MinIO, got %d", c.patches)
+	}
+}
+
+var _ = Describe("statusWriter", func() {
+	const (
+		timeout  = 30 * time.Second
+		interval = 250 * time.Millisecond
+	)
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "minio-status-writer", Namespace: "default"}
+
+	// 只负责 conditionType 的 field manager
+	conditionWriter := func(fieldManager, conditionType string) *statusWriter {
+		return newStatusWriter(k8sClient, fieldManager, func(status *miniov1alpha1.MinIOStatus) miniov1alpha1.MinIOStatus {
+			return miniov1alpha1.MinIOStatus{Conditions: filterConditions(status.Conditions, conditionType)}
+		})
+	}
+	applyCondition := func(w *statusWriter, conditionType string, status metav1.ConditionStatus) error {
+		var minio miniov1alpha1.MinIO
+		if err := k8sClient.Get(ctx, key, &minio); err != nil {
+			return err
+		}
+		setCondition(&minio, conditionType, status, "Test", "")
+		return w.Apply(ctx, &minio)
+	}
+	conditionStatus := func(conditionType string) metav1.ConditionStatus {
+		var minio miniov1alpha1.MinIO
+		if err := k8sClient.Get(ctx, key, &minio); err != nil {
+			return ""
+		}
+		if cond := meta.FindStatusCondition(minio.Status.Conditions, conditionType); cond != nil {
+			return cond.Status
+		}
+		return ""
+	}
+
+	It("keeps fields of different field managers and counts conflicts", func() {
+		minio := &miniov1alpha1.MinIO{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: miniov1alpha1.MinIOSpec{
+				Image: "minio/minio:latest",
+				Pools: []miniov1alpha1.Pool{{Name: "pool-0", Servers: 4, VolumesPerServer: 1}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, minio)).To(Succeed())
+
+		By("two field managers apply their own conditions")
+		writerA := conditionWriter("test-manager-a", "TestA")
+		writerB := conditionWriter("test-manager-b", "TestB")
+		Expect(applyCondition(writerA, "TestA", metav1.ConditionTrue)).To(Succeed())
+		Expect(applyCondition(writerB, "TestB", metav1.ConditionTrue)).To(Succeed())
+		Eventually(func() []metav1.ConditionStatus {
+			return []metav1.ConditionStatus{conditionStatus("TestA"), conditionStatus("TestB")}
+		}, timeout, interval).Should(Equal([]metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionTrue}))
+
+		By("a field manager applying a field owned by another one is counted as a conflict")
+		conflicts := testutil.ToFloat64(statusConflicts.WithLabelValues("test-manager-c"))
+		writerC := conditionWriter("test-manager-c", "TestA")
+		Expect(applyCondition(writerC, "TestA", metav1.ConditionFalse)).To(Succeed())
+		Expect(testutil.ToFloat64(statusConflicts.WithLabelValues("test-manager-c"))).To(Equal(conflicts + 1))
+		Expect(conditionStatus("TestA")).To(Equal(metav1.ConditionFalse))
+		Expect(conditionStatus("TestB")).To(Equal(metav1.ConditionTrue))
+
+		Expect(k8sClient.Delete(ctx, minio)).To(Succeed())
+	})
+})
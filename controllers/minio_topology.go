@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 服务池拓扑变化（如新增服务池）时同时重启所有服务池，返回 true 表示本次调谐中触发了重启
+// MinIO 要求集群内所有节点的启动参数一致，逐个滚动更新时新旧节点无法组成集群，
+// 因此先更新所有 StatefulSet 的 Pod 模板并取消 partition，再一次性删除所有旧 Pod
+// withConfig 为 true 时配置 Secret 的变化（如轮换 root 凭证）也需要同时重启
+func (r *MinIOReconciler) restartForTopologyChange(ctx context.Context, minio *miniov1alpha1.MinIO, podAnnotations map[string]string, withConfig bool) (bool, error) {
+	configHash := podAnnotations[miniov1alpha1.ConfigurationHashAnnotation]
+	topology := minio.ServerTopologyHash()
+	stalePod := func(annotations map[string]string) bool {
+		return annotations[miniov1alpha1.ServerTopologyAnnotation] != topology ||
+			withConfig && annotations[miniov1alpha1.ConfigurationHashAnnotation] != configHash
+	}
+
+	var stale []*miniov1alpha1.Pool
+	for i := range minio.Spec.Pools {
+		pool := &minio.Spec.Pools[i]
+		ss, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Get(ctx, minio.PoolStatefulSetName(pool), metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// 新增的服务池由 checkStatefulSet 创建
+				continue
+			}
+			return false, err
+		}
+		if !stalePod(ss.Spec.Template.Annotations) {
+			continue
+		}
+
+		expectedSs := utils.NewStatefulSetForMinIOPool(minio, pool, podAnnotations)
+		partition := int32(0)
+		ss.Spec.Template = expectedSs.Spec.Template
+		ss.Spec.UpdateStrategy = expectedSs.Spec.UpdateStrategy
+		ss.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+		if _, err := r.KubeClient.AppsV1().StatefulSets(minio.Namespace).Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("update StatefulSet %s/%s error, %s", ss.Namespace, ss.Name, err)
+			return false, err
+		}
+		stale = append(stale, pool)
+	}
+	if len(stale) == 0 {
+		return false, nil
+	}
+
+	// 删除所有使用旧启动参数的 Pod，由 StatefulSet 按新模板同时重建
+	names := make([]string, 0, len(stale))
+	for _, pool := range stale {
+		names = append(names, pool.Name)
+		var podList corev1.PodList
+		if err := r.List(ctx, &podList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPoolLabels(pool))); err != nil {
+			klog.Errorf("query Pod list of pool %s error, %s", pool.Name, err)
+			return false, err
+		}
+		for j := range podList.Items {
+			pod := &podList.Items[j]
+			if !pod.DeletionTimestamp.IsZero() || !stalePod(pod.Annotations) {
+				continue
+			}
+			if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+		}
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ServerTopologyChanged", "MinIO server pools or credentials changed, restarting pools %v together", names)
+
+	return true, nil
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// 单次健康检查的超时时间
+const healthCheckTimeout = 10 * time.Second
+
+// 创建 transport
+func createTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   15 * time.Second,
+		KeepAlive: 15 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConnsPerHost:   1024,
+		IdleConnTimeout:       15 * time.Second,
+		ResponseHeaderTimeout: 15 * time.Minute,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ExpectContinueTimeout: 15 * time.Second,
+		// Go net/http automatically unzip if content-type is
+		// gzip disable this feature, as we are always interested
+		// in raw stream.
+		DisableCompression: true,
+		TLSClientConfig: &tls.Config{
+			// Can't use SSLv3 because of POODLE and BEAST
+			// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
+			// Can't use TLSv1.1 because of RC4 cipher usage
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+
+	return transport
+}
+
+// 创建访问 MinIO 服务的 transport，开启 TLS 时信任实例证书的 CA、外部 CA 及集群 CA
+func newMinIOTransport(ctx context.Context, kubeClient kubernetes.Interface, minio *miniov1alpha1.MinIO) (*http.Transport, error) {
+	tr := createTransport()
+	if !minio.TLS() {
+		return tr, nil
+	}
+	if minio.Spec.InsecureSkipVerify {
+		tr.TLSClientConfig.InsecureSkipVerify = true
+		return tr, nil
+	}
+
+	rootCAs, err := minioRootCAs(ctx, kubeClient, minio)
+	if err != nil {
+		return nil, err
+	}
+	tr.TLSClientConfig.RootCAs = rootCAs
+
+	return tr, nil
+}
+
+// 收集 MinIO 实例使用的 CA 证书，在系统 CA 的基础上添加证书 Secret 中的 ca.crt 及自签名证书、外部 CA 证书和集群 CA
+func minioRootCAs(ctx context.Context, kubeClient kubernetes.Interface, minio *miniov1alpha1.MinIO) (*x509.CertPool, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		klog.Warningf("load system cert pool error, %s", err)
+		rootCAs = x509.NewCertPool()
+	}
+
+	for _, secret := range minio.CertSecrets() {
+		s, err := kubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get certificate Secret %s error, %w", secret.Name, err)
+		}
+		rootCAs.AppendCertsFromPEM(s.Data["ca.crt"])
+		rootCAs.AppendCertsFromPEM(s.Data[utils.CertificateKey(secret)])
+	}
+
+	for _, secret := range minio.Spec.ExternalCACertSecrets {
+		s, err := kubeClient.CoreV1().Secrets(minio.Namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get CA certificate Secret %s error, %w", secret.Name, err)
+		}
+		if !rootCAs.AppendCertsFromPEM(s.Data["ca.crt"]) {
+			return nil, fmt.Errorf("no CA certificate found in Secret %s", secret.Name)
+		}
+	}
+
+	// 自动签发的证书由集群 CA 签发
+	cm, err := kubeClient.CoreV1().ConfigMaps(minio.Namespace).Get(ctx, "kube-root-ca.crt", metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("get cluster CA certificate error, %w", err)
+	}
+	if err == nil {
+		rootCAs.AppendCertsFromPEM([]byte(cm.Data["ca.crt"]))
+	}
+
+	return rootCAs, nil
+}
+
+// 使用实例的 CA 证书访问 MinIO 服务的健康检查接口，超过 healthCheckTimeout 未返回时视为失败
+func minioHealthCheck(ctx context.Context, kubeClient kubernetes.Interface, minio *miniov1alpha1.MinIO) (bool, error) {
+	tr, err := newMinIOTransport(ctx, kubeClient, minio)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return minio.MinIOHealthCheck(ctx, tr)
+}
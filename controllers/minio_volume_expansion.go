@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PVC 进入 FileSystemResizePending 状态后等待在线扩容的时间，超时后重启 Pod 完成文件系统扩容
+const fileSystemResizeWait = 2 * time.Minute
+
+// 模板中请求的容量大于 PVC 时进行在线扩容，返回 true 表示修改了 PVC 的请求容量
+// 无法扩容时只产生一次事件，之后由 MinIOStatusReconciler 在 PVCStatus 中持续记录扩容失败及原因
+func (r *MinIOReconciler) expandPVC(ctx context.Context, minio *miniov1alpha1.MinIO, expected, found *corev1.PersistentVolumeClaim) (bool, error) {
+	desired := expected.Spec.Resources.Requests.Storage()
+	current := found.Spec.Resources.Requests.Storage()
+	if desired.IsZero() || desired.Cmp(*current) <= 0 {
+		return false, nil
+	}
+
+	if found.Spec.StorageClassName == nil || *found.Spec.StorageClassName == "" {
+		if pvcResizeFailed(minio, found.Name) {
+			return false, nil
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeWarning, "VolumeExpansionNotAllowed", "PVC %s has no StorageClass, can not be expanded", found.Name)
+		return false, nil
+	}
+	sc, err := r.KubeClient.StorageV1().StorageClasses().Get(ctx, *found.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("get StorageClass %s error, %s", *found.Spec.StorageClassName, err)
+		return false, err
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		if pvcResizeFailed(minio, found.Name) {
+			return false, nil
+		}
+		r.Recorder.Eventf(minio, corev1.EventTypeWarning, "VolumeExpansionNotAllowed", "StorageClass %s of PVC %s does not allow volume expansion", sc.Name, found.Name)
+		return false, nil
+	}
+
+	if found.Spec.Resources.Requests == nil {
+		found.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	found.Spec.Resources.Requests[corev1.ResourceStorage] = desired.DeepCopy()
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "PVCExpanding", "Expanding PVC %s from %s to %s", found.Name, current, desired)
+
+	return true, nil
+}
+
+// 返回 PVCStatus 中是否已记录 PVC 扩容失败
+func pvcResizeFailed(minio *miniov1alpha1.MinIO, name string) bool {
+	for _, ps := range minio.Status.PVCStatus {
+		if ps.Name == name {
+			return ps.ResizeStatus == miniov1alpha1.PVCResizeFailed
+		}
+	}
+	return false
+}
+
+// CSI 驱动不支持在线扩展文件系统时，PVC 会一直处于 FileSystemResizePending 状态，
+// 需要逐个重启使用该 PVC 的 Pod，返回 true 表示还有 PVC 在等待文件系统扩容
+func (r *MinIOReconciler) restartPodsForResize(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) (bool, error) {
+	var pvcList corev1.PersistentVolumeClaimList
+	if err := r.List(ctx, &pvcList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPoolLabels(pool))); err != nil {
+		klog.Errorf("query PVC list of pool %s error, %s", pool.Name, err)
+		return false, err
+	}
+
+	// PVC 名称到 Pod 名称的映射
+	podNames := make(map[string]string)
+	for i := 0; i < pool.Servers; i++ {
+		for j := 0; j < pool.VolumesPerServer; j++ {
+			podNames[utils.PersistentVolumeClaimName(minio, pool, i, j)] = minio.PoolPodName(pool, i)
+		}
+	}
+
+	pending := false
+	restart := ""
+	var pendingPVC string
+	for _, pvc := range pvcList.Items {
+		for _, cond := range pvc.Status.Conditions {
+			if cond.Type != corev1.PersistentVolumeClaimFileSystemResizePending || cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			pending = true
+			if restart == "" && time.Since(cond.LastTransitionTime.Time) > fileSystemResizeWait {
+				restart = podNames[pvc.Name]
+				pendingPVC = pvc.Name
+			}
+		}
+	}
+	if restart == "" {
+		return pending, nil
+	}
+
+	// 每次只重启一个 Pod，其他 Pod 全部就绪且集群健康时才继续
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPoolLabels(pool))); err != nil {
+		klog.Errorf("query Pod list of pool %s error, %s", pool.Name, err)
+		return true, err
+	}
+	var target *corev1.Pod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !utils.IsPodReady(pod) {
+			return true, nil
+		}
+		if pod.Name == restart {
+			target = pod
+		}
+	}
+	if target == nil {
+		return true, nil
+	}
+	if healthy, err := r.minioHealthy(ctx, minio); !healthy {
+		klog.Infof("MinIO %s/%s is not healthy, delay restarting Pod %s, %v", minio.Namespace, minio.Name, target.Name, err)
+		return true, nil
+	}
+
+	klog.Infof("restart MinIO Pod %s/%s to finish file system resize", target.Namespace, target.Name)
+	if err := r.Delete(ctx, target, client.Preconditions{UID: &target.UID}); err != nil {
+		return true, client.IgnoreNotFound(err)
+	}
+	r.Recorder.Eventf(minio, corev1.EventTypeNormal, "ServerPodRestarted", "MinIO Pod %s restarted to finish file system resize of PVC %s", target.Name, pendingPVC)
+
+	return true, nil
+}
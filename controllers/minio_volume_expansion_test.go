@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"minio-operator/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestPVC(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, server int, storageClass *string, size string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      utils.PersistentVolumeClaimName(minio, pool, server, 0),
+			Namespace: minio.Namespace,
+			Labels:    minio.MinIOPoolLabels(pool),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: storageClass,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			},
+		},
+	}
+}
+
+func newTestStorageClass(name string, allowExpansion *bool) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: name},
+		Provisioner:          "test",
+		AllowVolumeExpansion: allowExpansion,
+	}
+}
+
+// 为 PVC 设置持续 pendingFor 时间的 FileSystemResizePending 状态
+func fileSystemResizePending(pvc *corev1.PersistentVolumeClaim, pendingFor time.Duration) *corev1.PersistentVolumeClaim {
+	pvc.Status.Conditions = []corev1.PersistentVolumeClaimCondition{{
+		Type:               corev1.PersistentVolumeClaimFileSystemResizePending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-pendingFor)),
+	}}
+	return pvc
+}
+
+func TestExpandPVC(t *testing.T) {
+	standard := "standard"
+	empty := ""
+	allow, deny := true, false
+	tests := []struct {
+		name         string
+		storageClass *string
+		classes      []runtime.Object
+		resizeFailed bool
+		current      string
+		desired      string
+		wantExpanded bool
+		wantEvent    string
+	}{
+		{name: "same size", storageClass: &standard, classes: []runtime.Object{newTestStorageClass(standard, &allow)}, current: "10Gi", desired: "10Gi"},
+		{name: "shrink is ignored", storageClass: &standard, classes: []runtime.Object{newTestStorageClass(standard, &allow)}, current: "20Gi", desired: "10Gi"},
+		{name: "expansion allowed", storageClass: &standard, classes: []runtime.Object{newTestStorageClass(standard, &allow)}, current: "10Gi", desired: "20Gi", wantExpanded: true, wantEvent: "PVCExpanding"},
+		{name: "no StorageClass", storageClass: nil, current: "10Gi", desired: "20Gi", wantEvent: "VolumeExpansionNotAllowed"},
+		{name: "empty StorageClass", storageClass: &empty, current: "10Gi", desired: "20Gi", wantEvent: "VolumeExpansionNotAllowed"},
+		{name: "allowVolumeExpansion unset", storageClass: &standard, classes: []runtime.Object{newTestStorageClass(standard, nil)}, current: "10Gi", desired: "20Gi", wantEvent: "VolumeExpansionNotAllowed"},
+		{name: "allowVolumeExpansion false", storageClass: &standard, classes: []runtime.Object{newTestStorageClass(standard, &deny)}, current: "10Gi", desired: "20Gi", wantEvent: "VolumeExpansionNotAllowed"},
+		{name: "failure already recorded warns once", storageClass: &standard, classes: []runtime.Object{newTestStorageClass(standard, &deny)}, resizeFailed: true, current: "10Gi", desired: "20Gi"},
+		{name: "no StorageClass failure already recorded", storageClass: nil, resizeFailed: true, current: "10Gi", desired: "20Gi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO(4, 1)
+			pool := &minio.Spec.Pools[0]
+			found := newTestPVC(minio, pool, 0, tt.storageClass, tt.current)
+			expected := newTestPVC(minio, pool, 0, tt.storageClass, tt.desired)
+			if tt.resizeFailed {
+				minio.Status.PVCStatus = []miniov1alpha1.PVCStatus{{Name: found.Name, ResizeStatus: miniov1alpha1.PVCResizeFailed}}
+			}
+
+			r := newTestReconciler(t, nil, tt.classes...)
+			expanded, err := r.expandPVC(context.TODO(), minio, expected, found)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expanded != tt.wantExpanded {
+				t.Errorf("expanded = %v, want %v", expanded, tt.wantExpanded)
+			}
+			want := resource.MustParse(tt.current)
+			if tt.wantExpanded {
+				want = resource.MustParse(tt.desired)
+			}
+			if got := found.Spec.Resources.Requests[corev1.ResourceStorage]; got.Cmp(want) != 0 {
+				t.Errorf("requested storage = %s, want %s", got.String(), want.String())
+			}
+			events := recordedEvents(r)
+			if tt.wantEvent == "" && len(events) != 0 {
+				t.Errorf("events = %v, want none", events)
+			}
+			if tt.wantEvent != "" && (len(events) != 1 || !strings.Contains(events[0], tt.wantEvent)) {
+				t.Errorf("events = %v, want %s", events, tt.wantEvent)
+			}
+		})
+	}
+
+	t.Run("missing StorageClass returns an error", func(t *testing.T) {
+		minio := newTestMinIO(4, 1)
+		pool := &minio.Spec.Pools[0]
+		r := newTestReconciler(t, nil)
+		if _, err := r.expandPVC(context.TODO(), minio, newTestPVC(minio, pool, 0, &standard, "20Gi"), newTestPVC(minio, pool, 0, &standard, "10Gi")); err == nil {
+			t.Errorf("expandPVC returned no error for a missing StorageClass")
+		}
+	})
+}
+
+func TestRestartPodsForResize(t *testing.T) {
+	tests := []struct {
+		name        string
+		pending     map[int]time.Duration
+		notReady    map[int]bool
+		unhealthy   bool
+		wantPending bool
+		wantDeleted []int
+	}{
+		{name: "no pending resize"},
+		{name: "pending within the wait", pending: map[int]time.Duration{1: time.Minute}, wantPending: true},
+		{name: "pending past the wait restarts the pod", pending: map[int]time.Duration{1: 3 * time.Minute}, wantPending: true, wantDeleted: []int{1}},
+		{name: "only one pod restarts at a time", pending: map[int]time.Duration{1: 3 * time.Minute, 2: 3 * time.Minute, 3: 3 * time.Minute}, wantPending: true, wantDeleted: []int{1}},
+		{name: "waits for every pod to be ready", pending: map[int]time.Duration{1: 3 * time.Minute}, notReady: map[int]bool{2: true}, wantPending: true},
+		{name: "waits for MinIO to be healthy", pending: map[int]time.Duration{1: 3 * time.Minute}, unhealthy: true, wantPending: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO(4, 1)
+			pool := &minio.Spec.Pools[0]
+			standard := "standard"
+			var objs []client.Object
+			for i := 0; i < pool.Servers; i++ {
+				pvc := newTestPVC(minio, pool, i, &standard, "10Gi")
+				if d, ok := tt.pending[i]; ok {
+					fileSystemResizePending(pvc, d)
+				}
+				pod := newTestPod(minio, pool, i, "rev", !tt.notReady[i])
+				pod.UID = types.UID(pod.Name)
+				objs = append(objs, pvc, pod)
+			}
+
+			r := newTestReconciler(t, objs)
+			r.healthCheck = func(ctx context.Context, minio *miniov1alpha1.MinIO) (bool, error) {
+				return !tt.unhealthy, nil
+			}
+			pending, err := r.restartPodsForResize(context.TODO(), minio, pool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pending != tt.wantPending {
+				t.Errorf("pending = %v, want %v", pending, tt.wantPending)
+			}
+
+			var podList corev1.PodList
+			if err := r.List(context.TODO(), &podList, client.InNamespace(minio.Namespace)); err != nil {
+				t.Fatal(err)
+			}
+			remaining := map[string]bool{}
+			for _, pod := range podList.Items {
+				remaining[pod.Name] = true
+			}
+			for _, i := range tt.wantDeleted {
+				if remaining[minio.PoolPodName(pool, i)] {
+					t.Errorf("Pod %s was not restarted", minio.PoolPodName(pool, i))
+				}
+			}
+			if len(remaining) != pool.Servers-len(tt.wantDeleted) {
+				t.Errorf("%d Pods remain, want %d", len(remaining), pool.Servers-len(tt.wantDeleted))
+			}
+			events := recordedEvents(r)
+			if len(events) != len(tt.wantDeleted) {
+				t.Errorf("events = %v, want %d ServerPodRestarted", events, len(tt.wantDeleted))
+			}
+		})
+	}
+}
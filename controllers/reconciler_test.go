@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// 使用 fake client 创建 MinIOReconciler，objs 由 controller-runtime client 读写，kubeObjs 由 KubeClient 读写
+func newTestReconciler(t *testing.T, objs []client.Object, kubeObjs ...runtime.Object) *MinIOReconciler {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := miniov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return &MinIOReconciler{
+		Client:       c,
+		KubeClient:   kubefake.NewSimpleClientset(kubeObjs...),
+		Scheme:       scheme,
+		Recorder:     record.NewFakeRecorder(100),
+		statusWriter: newStatusWriter(c, minioFieldManager, minioStatusFields),
+	}
+}
+
+func newTestMinIO(servers, volumes int) *miniov1alpha1.MinIO {
+	return &miniov1alpha1.MinIO{
+		ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "default", UID: "minio-uid"},
+		Spec: miniov1alpha1.MinIOSpec{
+			Image: "minio/minio",
+			Pools: []miniov1alpha1.Pool{{Name: "pool-0", Servers: servers, VolumesPerServer: volumes}},
+		},
+	}
+}
+
+// 创建服务池中第 index 个 Pod，revision 为 Pod 的 controller-revision-hash
+func newTestPod(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, index int, revision string, ready bool) *corev1.Pod {
+	labels := minio.MinIOPoolLabels(pool)
+	labels[appsv1.ControllerRevisionHashLabelKey] = revision
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: minio.PoolPodName(pool, index), Namespace: minio.Namespace, Labels: labels},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+// 返回 fake recorder 中记录的事件
+func recordedEvents(r *MinIOReconciler) []string {
+	recorder := r.Recorder.(*record.FakeRecorder)
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -52,11 +53,19 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var enableMinIOController, enableStatusController, enableHealthChecker bool
+	var minioConcurrency, statusConcurrency, healthCheckerConcurrency int
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableMinIOController, "enable-minio-controller", true, "Enable the controller deploying MinIO instances.")
+	flag.BoolVar(&enableStatusController, "enable-status-controller", true, "Enable the controller reporting MinIO status.")
+	flag.BoolVar(&enableHealthChecker, "enable-health-checker", true, "Enable the controller checking MinIO health.")
+	flag.IntVar(&minioConcurrency, "minio-concurrency", 1, "The number of MinIO instances deployed concurrently.")
+	flag.IntVar(&statusConcurrency, "status-concurrency", 1, "The number of MinIO instances whose status is reported concurrently.")
+	flag.IntVar(&healthCheckerConcurrency, "health-checker-concurrency", 4, "The number of MinIO instances health checked concurrently.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -89,13 +98,49 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controllers.MinIOReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "MinIO")
+	// 所有 controller 共用一个 clientset
+	kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create kubernetes clientset")
 		os.Exit(1)
 	}
+
+	if enableMinIOController {
+		if err = (&controllers.MinIOReconciler{
+			Client:                  mgr.GetClient(),
+			KubeClient:              kubeClient,
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("minio-controller"),
+			MaxConcurrentReconciles: minioConcurrency,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "MinIO")
+			os.Exit(1)
+		}
+	}
+	if enableStatusController {
+		if err = (&controllers.MinIOStatusReconciler{
+			Client:                  mgr.GetClient(),
+			KubeClient:              kubeClient,
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("minio-status-controller"),
+			MaxConcurrentReconciles: statusConcurrency,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "MinIOStatus")
+			os.Exit(1)
+		}
+	}
+	if enableHealthChecker {
+		if err = (&controllers.MinIOHealthCheckerReconciler{
+			Client:                  mgr.GetClient(),
+			KubeClient:              kubeClient,
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("minio-health-checker"),
+			MaxConcurrentReconciles: healthCheckerConcurrency,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "MinIOHealthChecker")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"strings"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 生成 ECDSA 私钥，返回 PEM 编码的私钥
+func NewPrivateKey() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(miniov1alpha1.DefaultEllipticCurve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// 使用 PEM 编码的私钥生成包含 MinIO 所有域名的证书请求
+func NewCertificateRequest(minio *miniov1alpha1.MinIO, keyPEM []byte) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid PEM encoded private key")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   "system:node:" + minio.MinIOFQDNServiceName(),
+			Organization: miniov1alpha1.DefaultOrgName,
+		},
+		DNSNames: minio.CertificateDNSNames(),
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// 返回证书中域名列表的哈希值
+func CertificateDNSHash(minio *miniov1alpha1.MinIO) string {
+	return ComputeHash(strings.Join(minio.CertificateDNSNames(), ","))
+}
+
+// 根据 MinIO 实例构建 CSR，由 kubelet-serving 签发者签发服务端证书
+func NewCertificateSigningRequestForMinIO(minio *miniov1alpha1.MinIO, csrPEM []byte) *certificatesv1.CertificateSigningRequest {
+	return &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   minio.CSRName(),
+			Labels: minio.MinIOPodLabels(),
+			Annotations: map[string]string{
+				miniov1alpha1.CertificateDNSHashAnnotation: CertificateDNSHash(minio),
+			},
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:    csrPEM,
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+		},
+	}
+}
+
+// 根据签发的证书和私钥构建 MinIO 的证书 Secret
+func NewTLSSecretForMinIO(minio *miniov1alpha1.MinIO, certPEM, keyPEM []byte, dnsHash string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            minio.TLSSecretName(),
+			Namespace:       minio.Namespace,
+			Labels:          minio.MinIOPodLabels(),
+			OwnerReferences: minio.OwnerRef(),
+			Annotations: map[string]string{
+				miniov1alpha1.CertificateDNSHashAnnotation: dnsHash,
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+}
+
+// 根据 MinIO 实例构建 cert-manager 的 Certificate，签发的证书保存在同名 Secret 中
+func NewCertManagerCertificateForMinIO(minio *miniov1alpha1.MinIO) *unstructured.Unstructured {
+	certManager := minio.Spec.CertManager
+	issuerRef := map[string]interface{}{
+		"name": certManager.IssuerRef.Name,
+	}
+	if certManager.IssuerRef.Kind != "" {
+		issuerRef["kind"] = certManager.IssuerRef.Kind
+	}
+	if certManager.IssuerRef.Group != "" {
+		issuerRef["group"] = certManager.IssuerRef.Group
+	}
+
+	var dnsNames []interface{}
+	for _, name := range minio.CertificateDNSNames() {
+		dnsNames = append(dnsNames, name)
+	}
+	spec := map[string]interface{}{
+		"secretName": minio.CertManagerSecretName(),
+		"dnsNames":   dnsNames,
+		"issuerRef":  issuerRef,
+		"usages":     []interface{}{"digital signature", "key encipherment", "server auth", "client auth"},
+		"privateKey": map[string]interface{}{
+			"algorithm": "ECDSA",
+			"size":      int64(256),
+		},
+	}
+	if certManager.Duration != nil {
+		spec["duration"] = certManager.Duration.Duration.String()
+	}
+	if certManager.RenewBefore != nil {
+		spec["renewBefore"] = certManager.RenewBefore.Duration.String()
+	}
+
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	certificate.SetGroupVersionKind(schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"})
+	certificate.SetName(minio.CertManagerSecretName())
+	certificate.SetNamespace(minio.Namespace)
+	certificate.SetLabels(minio.MinIOPodLabels())
+	certificate.SetOwnerReferences(minio.OwnerRef())
+	return certificate
+}
+
+// 解析 PEM 编码的证书，返回第一个证书
+func ParseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid PEM encoded certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// 返回证书 Secret 中证书的 key
+func CertificateKey(secret miniov1alpha1.ExternalCertSecret) string {
+	if secret.Type == miniov1alpha1.CertSecretTypeMinIO {
+		return "public.crt"
+	}
+	return corev1.TLSCertKey
+}
+
+// 证书剩余有效期不足三分之一时需要续期
+func CertificateNeedsRenewal(cert *x509.Certificate, now time.Time) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotAfter.Sub(now) < lifetime/3
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestCertificateNeedsRenewal(t *testing.T) {
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(90 * 24 * time.Hour)}
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"just issued", notBefore, false},
+		{"more than a third left", notBefore.Add(59 * 24 * time.Hour), false},
+		{"exactly a third left", notBefore.Add(60 * 24 * time.Hour), false},
+		{"less than a third left", notBefore.Add(61 * 24 * time.Hour), true},
+		{"expired", notBefore.Add(91 * 24 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CertificateNeedsRenewal(cert, tt.now); got != tt.want {
+				t.Errorf("CertificateNeedsRenewal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,17 +2,41 @@ package utils
 
 import (
 	"context"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"sort"
 
 	"k8s.io/klog/v2"
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// 查询 MinIO 实例的所有 ControllerRevision，按版本号升序排列
+func ListControllerRevisions(ctx context.Context, c client.Client, minio *miniov1alpha1.MinIO) ([]appsv1.ControllerRevision, error) {
+	var crList appsv1.ControllerRevisionList
+	if err := c.List(ctx, &crList, client.InNamespace(minio.Namespace), client.MatchingLabels(minio.MinIOPodLabels())); err != nil {
+		klog.Errorf("query ControllerRevision list of %s/%s error, %s", minio.Namespace, minio.Name, err)
+		return nil, err
+	}
+
+	// 只保留由该 MinIO 实例创建的 ControllerRevision
+	var revisions []appsv1.ControllerRevision
+	for _, cr := range crList.Items {
+		if metav1.IsControlledBy(&cr, minio) {
+			revisions = append(revisions, cr)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
 // 校验 ControllerRevision 是否存在
 func ExistControllerRevision(name, namespace string, client client.Client) (bool, *appsv1.ControllerRevision, error) {
 	found := &appsv1.ControllerRevision{}
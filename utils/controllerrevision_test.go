@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeClient(t *testing.T, objs ...client.Object) (client.Client, *runtime.Scheme) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := miniov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(), scheme
+}
+
+// 创建属于 minio 的第 revision 个 ControllerRevision，image 用于区分不同的规格
+func newTestControllerRevision(minio *miniov1alpha1.MinIO, image string, revision int64) *appsv1.ControllerRevision {
+	m := minio.DeepCopy()
+	m.Spec.Image = image
+	cr := m.NewControllerRevision(revision)
+	cr.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(minio, miniov1alpha1.GroupVersion.WithKind(miniov1alpha1.MinIOCRDResourceKind))}
+	return cr
+}
+
+func TestListControllerRevisions(t *testing.T) {
+	minio := newTestMinIO()
+	minio.UID = "minio-uid"
+	other := newTestMinIO()
+	other.UID = "other-uid"
+
+	notOwned := newTestControllerRevision(other, "minio/minio:other", 1)
+	notOwned.Name = "minio-not-owned"
+	c, _ := newFakeClient(t,
+		newTestControllerRevision(minio, "minio/minio:3", 3),
+		newTestControllerRevision(minio, "minio/minio:1", 1),
+		newTestControllerRevision(minio, "minio/minio:2", 2),
+		notOwned,
+	)
+
+	revisions, err := ListControllerRevisions(context.TODO(), c, minio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for _, cr := range revisions {
+		got = append(got, cr.Revision)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("revisions = %v, want [1 2 3]", got)
+	}
+}
+
+func TestExistControllerRevision(t *testing.T) {
+	minio := newTestMinIO()
+	cr := newTestControllerRevision(minio, "minio/minio", 1)
+	c, _ := newFakeClient(t, cr)
+
+	exist, found, err := ExistControllerRevision(cr.Name, cr.Namespace, c)
+	if err != nil || !exist || found.Name != cr.Name {
+		t.Errorf("ExistControllerRevision(%s) = %v, %v, %v, want true", cr.Name, exist, found, err)
+	}
+	exist, found, err = ExistControllerRevision("missing", cr.Namespace, c)
+	if err != nil || exist || found != nil {
+		t.Errorf("ExistControllerRevision(missing) = %v, %v, %v, want false", exist, found, err)
+	}
+}
+
+func TestCreateControllerRevision(t *testing.T) {
+	minio := newTestMinIO()
+	minio.UID = "minio-uid"
+	c, scheme := newFakeClient(t)
+
+	cr := minio.NewControllerRevision(1)
+	if err := CreateControllerRevision(minio, cr, c, scheme); err != nil {
+		t.Fatal(err)
+	}
+	var created appsv1.ControllerRevision
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, &created); err != nil {
+		t.Fatal(err)
+	}
+	if !metav1.IsControlledBy(&created, minio) {
+		t.Errorf("ControllerRevision owners = %v, want controlled by MinIO", created.OwnerReferences)
+	}
+	if created.Labels[miniov1alpha1.Revision] != "1" {
+		t.Errorf("revision label = %q, want 1", created.Labels[miniov1alpha1.Revision])
+	}
+}
+
+func TestRebuildControllerRevision(t *testing.T) {
+	minio := newTestMinIO()
+	minio.UID = "minio-uid"
+	oldCr := newTestControllerRevision(minio, "minio/minio:old", 1)
+	c, scheme := newFakeClient(t, oldCr)
+
+	newCr := newTestControllerRevision(minio, "minio/minio:new", 2)
+	newCr.OwnerReferences = nil
+	if err := RebuildControllerRevision(minio, oldCr, newCr, c, scheme); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := ListControllerRevisions(context.TODO(), c, minio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Name != newCr.Name || revisions[0].Revision != 2 {
+		t.Errorf("revisions = %v, want only %s", revisions, newCr.Name)
+	}
+}
+
+func TestDeleteControllerRevision(t *testing.T) {
+	minio := newTestMinIO()
+	cr := newTestControllerRevision(minio, "minio/minio", 1)
+	c, _ := newFakeClient(t, cr)
+
+	if err := DeleteControllerRevision(cr, c); err != nil {
+		t.Fatal(err)
+	}
+	if exist, _, _ := ExistControllerRevision(cr.Name, cr.Namespace, c); exist {
+		t.Errorf("ControllerRevision %s still exists", cr.Name)
+	}
+	if err := DeleteControllerRevision(cr, c); err == nil {
+		t.Errorf("deleting a missing ControllerRevision returned no error")
+	}
+}
@@ -1,119 +1,179 @@
 package utils
 
 import (
-	"context"
 	miniov1alpha1 "minio-operator/api/v1alpha1"
-	"reflect"
 	"strconv"
-	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	corev1 "k8s.io/api/core/v1"
 )
 
-// 校验是否需要对 MinIO 服务进行更新
-func MinioServerNeedToUpdate(oldMinio, newMinio miniov1alpha1.MinIO, oldPool, newPool miniov1alpha1.Pool) bool {
-	if oldMinio.Spec.Image != newMinio.Spec.Image ||
-		oldMinio.Spec.ImagePullPolicy != newMinio.Spec.ImagePullPolicy ||
-		oldMinio.Spec.ImagePullSecret.Name != newMinio.Spec.ImagePullSecret.Name ||
-		(oldMinio.Spec.Liveness != nil && newMinio.Spec.Liveness != nil && !reflect.DeepEqual(oldMinio.Spec.Liveness, newMinio.Spec.Liveness)) ||
-		(oldMinio.Spec.Readiness != nil && newMinio.Spec.Readiness != nil && !reflect.DeepEqual(oldMinio.Spec.Readiness, newMinio.Spec.Readiness)) ||
-		(oldMinio.Spec.Startup != nil && newMinio.Spec.Startup != nil && !reflect.DeepEqual(oldMinio.Spec.Startup, newMinio.Spec.Startup)) ||
-		(oldMinio.Spec.Affinity != nil && newMinio.Spec.Affinity != nil && !reflect.DeepEqual(oldMinio.Spec.Affinity, newMinio.Spec.Affinity)) {
-		return true
+// 根据 MinIO 服务池构建 StatefulSet 中使用的 Pod 模板，annotations 为配置、证书等外部资源内容的哈希值
+func NewPodTemplateForMinIOPool(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, annotations map[string]string) corev1.PodTemplateSpec {
+	mountPath := minio.MountPath()
+
+	// 设置 volumeMounts，卷由 StatefulSet 的 volumeClaimTemplates 提供
+	var volMounts []corev1.VolumeMount
+	if pool.VolumesPerServer == 1 {
+		volMounts = append(volMounts, corev1.VolumeMount{
+			Name:      PoolVolumeName(pool, 0),
+			MountPath: mountPath,
+		})
+	} else {
+		for j := 0; j < pool.VolumesPerServer; j++ {
+			volMounts = append(volMounts, corev1.VolumeMount{
+				Name:      PoolVolumeName(pool, j),
+				MountPath: mountPath + "-" + strconv.Itoa(j),
+			})
+		}
+	}
+
+	// 挂载配置 Secret 中的 config.env
+	volumes := []corev1.Volume{
+		{
+			Name: miniov1alpha1.ConfigurationVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: minio.ConfigurationSecretName(),
+					Items: []corev1.KeyToPath{
+						{
+							Key:  "config.env",
+							Path: "config.env",
+						},
+					},
+				},
+			},
+		},
+	}
+	volMounts = append(volMounts, corev1.VolumeMount{
+		Name:      miniov1alpha1.ConfigurationVolumeName,
+		MountPath: miniov1alpha1.CfgPath,
+		ReadOnly:  true,
+	})
+
+	// 开启 TLS 时挂载证书，并信任集群 CA 以便节点之间互相访问
+	if minio.TLS() {
+		volumes = append(volumes, newCertificateVolume(minio))
+		volMounts = append(volMounts, corev1.VolumeMount{
+			Name:      miniov1alpha1.CertificateVolumeName,
+			MountPath: miniov1alpha1.MinIOCertPath,
+			ReadOnly:  true,
+		})
 	}
 
-	if !reflect.DeepEqual(oldMinio.Spec.Resources, newMinio.Spec.Resources) {
-		return true
+	containers := []corev1.Container{
+		minioServerContainer(minio, pool, volMounts),
 	}
 
-	if reflect.DeepEqual(oldPool.NodeSelector, newPool.NodeSelector) {
-		return true
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: minio.MinIOPoolLabels(pool),
+			Annotations: map[string]string{
+				miniov1alpha1.ServerTopologyAnnotation: minio.ServerTopologyHash(),
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers:         containers,
+			Volumes:            volumes,
+			NodeSelector:       pool.NodeSelector,
+			ServiceAccountName: minio.Spec.ServiceAccountName,
+			Subdomain:          minio.MinIOHLServiceName(),
+			Affinity:           minio.Spec.Affinity,
+			Tolerations:        minio.Spec.Tolerations,
+			SecurityContext:    pool.SecurityContext,
+		},
 	}
 
-	return false
+	// 配置 Secret、证书等内容变化后 Pod 模板随之变化，触发滚动更新
+	for k, v := range annotations {
+		template.Annotations[k] = v
+	}
+	// 记录渲染结果的哈希值，任何影响 Pod 的变更都会改变哈希值并触发滚动更新
+	template.Annotations[miniov1alpha1.PodTemplateHashAnnotation] = ComputeHash(template)
+
+	return template
 }
 
-// 返回 Pod 列表
-func NewPodsForMinIOPool(ctx context.Context, minio miniov1alpha1.MinIO, pool miniov1alpha1.Pool) (pods []corev1.Pod) {
-	servers := pool.Servers
-	volumesPerServer := pool.VolumesPerServer
-
-	for i := 0; i < servers; i++ {
-		var volumes []corev1.Volume
-		var volMounts []corev1.VolumeMount
-
-		// 设置 volumes
-		for j := 0; j < volumesPerServer; j++ {
-			volName := pool.VolumeClaimTemplate.Name + strconv.Itoa(j)
-			vol := corev1.Volume{
-				Name: volName,
-				VolumeSource: corev1.VolumeSource{
-					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: minio.Name + "-" + pool.Name + "-" + strconv.Itoa(i) + "-" + pool.VolumeClaimTemplate.Name + "-" + strconv.Itoa(j),
-					},
-				},
-			}
-			volumes = append(volumes, vol)
+// 按 MinIO --certs-dir 的目录结构挂载证书: 默认证书为 public.crt 和 private.key，
+// 其他证书放在以 Secret 名称命名的子目录中由 MinIO 按 SNI 选择，CA 证书放在 CAs 目录下
+func newCertificateVolume(minio *miniov1alpha1.MinIO) corev1.Volume {
+	var sources []corev1.VolumeProjection
+	for i, secret := range minio.CertSecrets() {
+		dir := ""
+		if i > 0 {
+			dir = secret.Name + "/"
 		}
-
-		var mountPath string
-		if strings.HasSuffix(minio.Spec.Mountpath, "/") {
-			mountPath = strings.TrimRight(minio.Spec.Mountpath, "/")
-		} else {
-			mountPath = minio.Spec.Mountpath
+		certKey, privateKey := CertificateKey(secret), corev1.TLSPrivateKeyKey
+		if secret.Type == miniov1alpha1.CertSecretTypeMinIO {
+			privateKey = "private.key"
 		}
-
-		// 设置 volumeMounts
-		if volumesPerServer == 1 {
-			volMount := corev1.VolumeMount{
-				Name:      pool.VolumeClaimTemplate.Name + strconv.Itoa(0),
-				MountPath: mountPath,
-			}
-			volMounts = append(volMounts, volMount)
-		} else {
-			for j := 0; j < volumesPerServer; j++ {
-				volMount := corev1.VolumeMount{
-					Name:      pool.VolumeClaimTemplate.Name + strconv.Itoa(j),
-					MountPath: mountPath + "-" + strconv.Itoa(j),
-				}
-				volMounts = append(volMounts, volMount)
-			}
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+				Items: []corev1.KeyToPath{
+					{Key: certKey, Path: dir + "public.crt"},
+					{Key: privateKey, Path: dir + "private.key"},
+				},
+			},
+		})
+		// cert-manager 签发的证书中可能不包含签发者的 CA 证书
+		if secret.Type == miniov1alpha1.CertSecretTypeCertManager {
+			sources = append(sources, newCAProjection(secret.Name, true))
 		}
+	}
+	for _, secret := range minio.Spec.ExternalCACertSecrets {
+		sources = append(sources, newCAProjection(secret.Name, false))
+	}
 
-		containers := []corev1.Container{
-			minioServerContainer(minio, pool, volMounts),
-		}
-		labels := minio.MinIOPodLabels()
-		labels[miniov1alpha1.PoolLabel] = pool.Name
-		podName := pool.Name + "-" + strconv.Itoa(i)
-
-		pod := corev1.Pod{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:            podName,
-				Namespace:       minio.Namespace,
-				Labels:          labels,
-				OwnerReferences: minio.OwnerRef(),
+	// 自动签发的证书由集群 CA 签发
+	sources = append(sources, corev1.VolumeProjection{
+		ConfigMap: &corev1.ConfigMapProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "kube-root-ca.crt"},
+			Items: []corev1.KeyToPath{
+				{Key: "ca.crt", Path: "CAs/kube-root-ca.crt"},
 			},
-			Spec: corev1.PodSpec{
-				Volumes:            volumes,
-				Containers:         containers,
-				NodeSelector:       pool.NodeSelector,
-				ServiceAccountName: minio.Spec.ServiceAccountName,
-				Hostname:           podName,
-				Subdomain:          minio.MinIOHLServiceName(),
-				Affinity:           minio.Spec.Affinity,
-				Tolerations:        minio.Spec.Tolerations,
+		},
+	})
+
+	return corev1.Volume{
+		Name: miniov1alpha1.CertificateVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: sources,
 			},
-		}
-		pods = append(pods, pod)
+		},
 	}
+}
 
-	return pods
+// 将 Secret 中的 ca.crt 挂载到 CAs 目录下
+func newCAProjection(name string, optional bool) corev1.VolumeProjection {
+	return corev1.VolumeProjection{
+		Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Items: []corev1.KeyToPath{
+				{Key: "ca.crt", Path: "CAs/" + name + ".crt"},
+			},
+			Optional: &optional,
+		},
+	}
+}
+
+// 返回 Pod 模板中记录的哈希值
+func PodTemplateHash(template *corev1.PodTemplateSpec) string {
+	return template.Annotations[miniov1alpha1.PodTemplateHashAnnotation]
+}
+
+// 返回服务池中第 index 个卷的名称，同时作为 volumeClaimTemplate 的名称
+func PoolVolumeName(pool *miniov1alpha1.Pool, index int) string {
+	name := miniov1alpha1.MinIOVolumeName
+	if pool.VolumeClaimTemplate != nil && pool.VolumeClaimTemplate.Name != "" {
+		name = pool.VolumeClaimTemplate.Name
+	}
+	return name + strconv.Itoa(index)
 }
 
-func minioServerContainer(m miniov1alpha1.MinIO, pool miniov1alpha1.Pool, volumeMounts []corev1.VolumeMount) corev1.Container {
+func minioServerContainer(m *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, volumeMounts []corev1.VolumeMount) corev1.Container {
 	consolePort := miniov1alpha1.ConsolePort
 	if m.TLS() {
 		consolePort = miniov1alpha1.ConsoleTLSPort
@@ -124,6 +184,8 @@ func minioServerContainer(m miniov1alpha1.MinIO, pool miniov1alpha1.Pool, volume
 		"--certs-dir", miniov1alpha1.MinIOCertPath,
 		"--console-address", ":" + strconv.Itoa(consolePort),
 	}
+	// 所有服务池使用相同的启动参数组成分布式集群
+	args = append(args, m.ServerEndpoints()...)
 
 	containerPorts := []corev1.ContainerPort{
 		{
@@ -134,8 +196,14 @@ func minioServerContainer(m miniov1alpha1.MinIO, pool miniov1alpha1.Pool, volume
 		},
 	}
 
-	// 设置默认环境变量，不开启奇偶校验
-	env := m.DefaultPodEnv()
+	// 用户设置的环境变量覆盖默认环境变量
+	env := mergeEnvVars(m.DefaultPodEnv(), m.GetEnvVars())
+	env = mergeEnvVars(env, []corev1.EnvVar{
+		{
+			Name:  "MINIO_CONFIG_ENV_FILE",
+			Value: miniov1alpha1.CfgFile,
+		},
+	})
 
 	return corev1.Container{
 		Name:            miniov1alpha1.MinIOServerName,
@@ -153,3 +221,33 @@ func minioServerContainer(m miniov1alpha1.MinIO, pool miniov1alpha1.Pool, volume
 		SecurityContext: pool.ContainerSecurityContext,
 	}
 }
+
+// 合并环境变量，overrides 中的同名环境变量覆盖 base 中的值并保持原有顺序
+func mergeEnvVars(base, overrides []corev1.EnvVar) []corev1.EnvVar {
+	env := make([]corev1.EnvVar, 0, len(base)+len(overrides))
+	index := make(map[string]int, len(base)+len(overrides))
+	for _, list := range [][]corev1.EnvVar{base, overrides} {
+		for _, e := range list {
+			if i, ok := index[e.Name]; ok {
+				env[i] = e
+				continue
+			}
+			index[e.Name] = len(env)
+			env = append(env, e)
+		}
+	}
+	return env
+}
+
+// 校验 Pod 是否处于 Ready 状态
+func IsPodReady(pod *corev1.Pod) bool {
+	if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// 返回 Pod 模板中 MinIO 容器
+func minioContainer(t *testing.T, template *corev1.PodTemplateSpec) *corev1.Container {
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == miniov1alpha1.MinIOServerName {
+			return &template.Spec.Containers[i]
+		}
+	}
+	t.Fatalf("container %s not found", miniov1alpha1.MinIOServerName)
+	return nil
+}
+
+func TestServerArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		pools     []miniov1alpha1.Pool
+		endpoints []string
+	}{
+		{
+			name:      "single server with single volume",
+			pools:     []miniov1alpha1.Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 1}},
+			endpoints: []string{"/export"},
+		},
+		{
+			name:      "single server with multiple volumes",
+			pools:     []miniov1alpha1.Pool{{Name: "pool-0", Servers: 1, VolumesPerServer: 2}},
+			endpoints: []string{"http://minio-ss-pool-0-0.miniohl.default.svc.cluster.local/export-{0...1}"},
+		},
+		{
+			name: "multiple pools",
+			pools: []miniov1alpha1.Pool{
+				{Name: "pool-0", Servers: 4, VolumesPerServer: 1},
+				{Name: "pool-1", Servers: 2, VolumesPerServer: 2},
+			},
+			endpoints: []string{
+				"http://minio-ss-pool-0-{0...3}.miniohl.default.svc.cluster.local/export",
+				"http://minio-ss-pool-1-{0...1}.miniohl.default.svc.cluster.local/export-{0...1}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO()
+			minio.Spec.Pools = tt.pools
+			for i := range minio.Spec.Pools {
+				template := NewPodTemplateForMinIOPool(minio, &minio.Spec.Pools[i], nil)
+				container := minioContainer(t, &template)
+				// 所有服务池使用相同的地址参数
+				args := container.Args[len(container.Args)-len(tt.endpoints):]
+				if !reflect.DeepEqual(args, tt.endpoints) {
+					t.Errorf("args of pool %s = %v, want %v", minio.Spec.Pools[i].Name, container.Args, tt.endpoints)
+				}
+			}
+		})
+	}
+}
+
+func TestPodTemplateHash(t *testing.T) {
+	runAsUser := int64(1000)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		change      func(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool)
+	}{
+		{name: "image", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) { m.Spec.Image = "minio/minio:new" }},
+		{name: "imagePullPolicy", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) { m.Spec.ImagePullPolicy = corev1.PullAlways }},
+		{name: "env", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.Env = []corev1.EnvVar{{Name: "MINIO_BROWSER", Value: "off"}}
+		}},
+		{name: "mountPath", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) { m.Spec.Mountpath = "/data" }},
+		{name: "enableCert", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) { m.Spec.EnableCert = true }},
+		{name: "externalCertSecrets", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.ExternalCertSecrets = []miniov1alpha1.ExternalCertSecret{{Name: "cert"}}
+		}},
+		{name: "serviceAccountName", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) { m.Spec.ServiceAccountName = "minio" }},
+		{name: "tolerations", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+		}},
+		{name: "resources", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}
+		}},
+		{name: "affinity", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}}
+		}},
+		{name: "liveness", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) { m.Spec.Liveness = &corev1.Probe{PeriodSeconds: 5} }},
+		{name: "readiness", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.Readiness = &corev1.Probe{PeriodSeconds: 5}
+		}},
+		{name: "startup", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) { m.Spec.Startup = &corev1.Probe{PeriodSeconds: 5} }},
+		{name: "lifecycle", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.Lifecycle = &corev1.Lifecycle{PreStop: &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"sleep", "5"}}}}
+		}},
+		{name: "servers", change: func(_ *miniov1alpha1.MinIO, p *miniov1alpha1.Pool) { p.Servers = 8 }},
+		{name: "another pool", change: func(m *miniov1alpha1.MinIO, _ *miniov1alpha1.Pool) {
+			m.Spec.Pools = append(m.Spec.Pools, miniov1alpha1.Pool{Name: "pool-1", Servers: 4, VolumesPerServer: 1})
+		}},
+		{name: "volumeClaimTemplate name", change: func(_ *miniov1alpha1.MinIO, p *miniov1alpha1.Pool) {
+			p.VolumeClaimTemplate = &corev1.PersistentVolumeClaim{}
+			p.VolumeClaimTemplate.Name = "disk"
+		}},
+		{name: "nodeSelector", change: func(_ *miniov1alpha1.MinIO, p *miniov1alpha1.Pool) { p.NodeSelector = map[string]string{"disk": "ssd"} }},
+		{name: "securityContext", change: func(_ *miniov1alpha1.MinIO, p *miniov1alpha1.Pool) {
+			p.SecurityContext = &corev1.PodSecurityContext{RunAsUser: &runAsUser}
+		}},
+		{name: "containerSecurityContext", change: func(_ *miniov1alpha1.MinIO, p *miniov1alpha1.Pool) {
+			p.ContainerSecurityContext = &corev1.SecurityContext{RunAsUser: &runAsUser}
+		}},
+		{name: "configuration hash", annotations: map[string]string{miniov1alpha1.ConfigurationHashAnnotation: "changed"}},
+	}
+
+	minio := newTestMinIO()
+	template := NewPodTemplateForMinIOPool(minio, &minio.Spec.Pools[0], nil)
+	base := PodTemplateHash(&template)
+	if base == "" {
+		t.Fatalf("pod template has no hash annotation")
+	}
+	again := NewPodTemplateForMinIOPool(minio, &minio.Spec.Pools[0], nil)
+	if hash := PodTemplateHash(&again); hash != base {
+		t.Errorf("hash of the same spec = %s, want %s", hash, base)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO()
+			if tt.change != nil {
+				tt.change(minio, &minio.Spec.Pools[0])
+			}
+			template := NewPodTemplateForMinIOPool(minio, &minio.Spec.Pools[0], tt.annotations)
+			if hash := PodTemplateHash(&template); hash == base {
+				t.Errorf("hash did not change after changing %s", tt.name)
+			}
+		})
+	}
+
+	// 不影响 Pod 的字段不改变哈希值
+	unchanged := map[string]func(m *miniov1alpha1.MinIO){
+		"reclaimStorage":       func(m *miniov1alpha1.MinIO) { m.Spec.ReclaimStorage = true },
+		"revisionHistoryLimit": func(m *miniov1alpha1.MinIO) { limit := int32(3); m.Spec.RevisionHistoryLimit = &limit },
+		"maxUnavailable": func(m *miniov1alpha1.MinIO) {
+			maxUnavailable := intstr.FromInt(2)
+			m.Spec.MaxUnavailable = &maxUnavailable
+		},
+	}
+	for name, change := range unchanged {
+		t.Run(name, func(t *testing.T) {
+			minio := newTestMinIO()
+			change(minio)
+			template := NewPodTemplateForMinIOPool(minio, &minio.Spec.Pools[0], nil)
+			if hash := PodTemplateHash(&template); hash != base {
+				t.Errorf("hash changed after changing %s", name)
+			}
+		})
+	}
+}
+
+func TestMergeEnvVars(t *testing.T) {
+	base := []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}
+	overrides := []corev1.EnvVar{{Name: "C", Value: "3"}, {Name: "A", Value: "4"}}
+	want := []corev1.EnvVar{{Name: "A", Value: "4"}, {Name: "B", Value: "2"}, {Name: "C", Value: "3"}}
+	if got := mergeEnvVars(base, overrides); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnvVars() = %v, want %v", got, want)
+	}
+	if got := mergeEnvVars(nil, nil); len(got) != 0 {
+		t.Errorf("mergeEnvVars(nil, nil) = %v, want empty", got)
+	}
+}
+
+func TestServerEnv(t *testing.T) {
+	minio := newTestMinIO()
+	minio.Spec.Env = []corev1.EnvVar{
+		{Name: "MINIO_ROOT_USER", Value: "admin"},
+		{Name: "MINIO_ROOT_PASSWORD", Value: "password"},
+		{Name: "MINIO_ACCESS_KEY", Value: "admin"},
+		{Name: "MINIO_SECRET_KEY", Value: "password"},
+		{Name: "MINIO_STORAGE_CLASS_STANDARD", Value: "EC:1"},
+		{Name: "MINIO_CONFIG_ENV_FILE", Value: "/etc/minio/config.env"},
+		{Name: "MINIO_BROWSER", Value: "off"},
+	}
+	template := NewPodTemplateForMinIOPool(minio, &minio.Spec.Pools[0], nil)
+	env := map[string]string{}
+	for _, e := range minioContainer(t, &template).Env {
+		env[e.Name] = e.Value
+	}
+
+	for _, name := range []string{"MINIO_ROOT_USER", "MINIO_ROOT_PASSWORD", "MINIO_ACCESS_KEY", "MINIO_SECRET_KEY"} {
+		if _, ok := env[name]; ok {
+			t.Errorf("root credential env %s passed to the server", name)
+		}
+	}
+	want := map[string]string{
+		// 用户设置的环境变量覆盖默认值，config.env 的路径不能被覆盖
+		"MINIO_STORAGE_CLASS_STANDARD": "EC:1",
+		"MINIO_STORAGE_CLASS_RRS":      "EC:1",
+		"MINIO_BROWSER":                "off",
+		"MINIO_CONFIG_ENV_FILE":        miniov1alpha1.CfgFile,
+	}
+	for name, value := range want {
+		if env[name] != value {
+			t.Errorf("env %s = %q, want %q", name, env[name], value)
+		}
+	}
+}
+
+func TestConfigurationMount(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuration *corev1.LocalObjectReference
+		wantSecret    string
+	}{
+		{name: "generated Secret", wantSecret: "minio" + miniov1alpha1.TenantConfigurationSecretSuffix},
+		{name: "user Secret", configuration: &corev1.LocalObjectReference{Name: "minio-env"}, wantSecret: "minio-env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO()
+			minio.Spec.Configuration = tt.configuration
+			template := NewPodTemplateForMinIOPool(minio, &minio.Spec.Pools[0], nil)
+
+			var volume *corev1.Volume
+			for i := range template.Spec.Volumes {
+				if template.Spec.Volumes[i].Name == miniov1alpha1.ConfigurationVolumeName {
+					volume = &template.Spec.Volumes[i]
+				}
+			}
+			if volume == nil || volume.Secret == nil {
+				t.Fatalf("volumes = %v, want Secret volume %s", template.Spec.Volumes, miniov1alpha1.ConfigurationVolumeName)
+			}
+			wantItems := []corev1.KeyToPath{{Key: "config.env", Path: "config.env"}}
+			if volume.Secret.SecretName != tt.wantSecret || !reflect.DeepEqual(volume.Secret.Items, wantItems) {
+				t.Errorf("configuration volume = %+v, want config.env of Secret %s", volume.Secret, tt.wantSecret)
+			}
+
+			var mount *corev1.VolumeMount
+			for _, m := range minioContainer(t, &template).VolumeMounts {
+				if m.Name == miniov1alpha1.ConfigurationVolumeName {
+					mount = m.DeepCopy()
+				}
+			}
+			if mount == nil || mount.MountPath != miniov1alpha1.CfgPath || !mount.ReadOnly {
+				t.Errorf("configuration mount = %+v, want read-only at %s", mount, miniov1alpha1.CfgPath)
+			}
+		})
+	}
+}
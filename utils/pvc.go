@@ -14,28 +14,97 @@ import (
 func NewPersistentVolumeClaimForMinIOPool(ctx context.Context, minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) (pvcs []*corev1.PersistentVolumeClaim) {
 	servers := pool.Servers
 	volumesPerServer := pool.VolumesPerServer
-	labels := minio.MinIOPodLabels()
-	labels[miniov1alpha1.PoolLabel] = pool.Name
 
 	for i := 0; i < servers; i++ {
 		for j := 0; j < volumesPerServer; j++ {
-			// pvc 名称规则: "MINIO名称-pool名称-pool索引-pool中设置的PVC名称-pod挂载的卷的索引"
-			name := minio.Name + "-" + pool.Name + "-" + strconv.Itoa(i) + "-" + pool.VolumeClaimTemplate.Name + "-" + strconv.Itoa(j)
-			pvc := &corev1.PersistentVolumeClaim{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      name,
-					Namespace: minio.Namespace,
-					Labels:    labels,
-				},
-				Spec: corev1.PersistentVolumeClaimSpec{
-					AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
-					Resources:        minio.Spec.Resources,
-					StorageClassName: pool.VolumeClaimTemplate.Spec.StorageClassName,
-				},
-			}
+			pvc := newVolumeClaimForMinIOPool(minio, pool, j)
+			pvc.Name = PersistentVolumeClaimName(minio, pool, i, j)
+			pvc.Namespace = minio.Namespace
 			pvcs = append(pvcs, pvc)
 		}
 	}
 
 	return pvcs
 }
+
+// 返回 StatefulSet 的 volumeClaimTemplates
+// PVC 由 checkPVC 预先创建并维护 OwnerReference，模板中不设置 OwnerReference
+func NewVolumeClaimTemplatesForMinIOPool(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool) (templates []corev1.PersistentVolumeClaim) {
+	for j := 0; j < pool.VolumesPerServer; j++ {
+		pvc := newVolumeClaimForMinIOPool(minio, pool, j)
+		pvc.OwnerReferences = nil
+		templates = append(templates, *pvc)
+	}
+	return templates
+}
+
+// 根据服务池的 VolumeClaimTemplate 渲染 PVC，合并 operator 的 Labels
+func newVolumeClaimForMinIOPool(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, volumeIndex int) *corev1.PersistentVolumeClaim {
+	labels := make(map[string]string)
+	var annotations map[string]string
+	var spec corev1.PersistentVolumeClaimSpec
+	if pool.VolumeClaimTemplate != nil {
+		template := pool.VolumeClaimTemplate.DeepCopy()
+		for k, v := range template.Labels {
+			labels[k] = v
+		}
+		annotations = template.Annotations
+		spec = template.Spec
+	}
+	if len(spec.AccessModes) == 0 {
+		spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	for k, v := range minio.MinIOPoolLabels(pool) {
+		labels[k] = v
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        PoolVolumeName(pool, volumeIndex),
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: spec,
+	}
+	// ReclaimStorage 为 true 时 PVC 随 MinIO 实例一起删除
+	if minio.Spec.ReclaimStorage {
+		pvc.OwnerReferences = minio.OwnerRef()
+	}
+
+	return pvc
+}
+
+// 根据回收策略更新 PVC 的 OwnerReference，返回 true 表示发生了变化
+func SyncPersistentVolumeClaimOwner(minio *miniov1alpha1.MinIO, pvc *corev1.PersistentVolumeClaim) bool {
+	owned := metav1.IsControlledBy(pvc, minio)
+	if minio.Spec.ReclaimStorage == owned {
+		return false
+	}
+
+	if minio.Spec.ReclaimStorage {
+		pvc.OwnerReferences = append(pvc.OwnerReferences, minio.OwnerRef()...)
+		return true
+	}
+	var ownerRefs []metav1.OwnerReference
+	for _, ref := range pvc.OwnerReferences {
+		if ref.UID != minio.UID {
+			ownerRefs = append(ownerRefs, ref)
+		}
+	}
+	pvc.OwnerReferences = ownerRefs
+	return true
+}
+
+// PVC 名称规则与 StatefulSet 保持一致: "卷名称-StatefulSet名称-pod索引"，卷名称为 "pool中设置的PVC名称+卷索引"
+func PersistentVolumeClaimName(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, serverIndex, volumeIndex int) string {
+	return PoolVolumeName(pool, volumeIndex) + "-" + minio.PoolStatefulSetName(pool) + "-" + strconv.Itoa(serverIndex)
+}
+
+// 直接创建 Pod 时使用的 PVC 名称: "MINIO名称-pool名称-pool索引-pool中设置的PVC名称-pod挂载的卷的索引"
+func LegacyPersistentVolumeClaimName(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, serverIndex, volumeIndex int) string {
+	var templateName string
+	if pool.VolumeClaimTemplate != nil {
+		templateName = pool.VolumeClaimTemplate.Name
+	}
+	return minio.Name + "-" + pool.Name + "-" + strconv.Itoa(serverIndex) + "-" + templateName + "-" + strconv.Itoa(volumeIndex)
+}
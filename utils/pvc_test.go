@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestNewPersistentVolumeClaimForMinIOPool(t *testing.T) {
+	storageClass := "fast"
+	block := corev1.PersistentVolumeBlock
+	dataSource := &corev1.TypedLocalObjectReference{Kind: "VolumeSnapshot", Name: "snapshot"}
+
+	minio := newTestMinIO()
+	minio.UID = "minio-uid"
+	pool := &minio.Spec.Pools[0]
+	pool.Servers = 2
+	pool.VolumesPerServer = 2
+	pool.VolumeClaimTemplate = &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "data",
+			Labels:      map[string]string{"team": "storage", miniov1alpha1.PoolLabel: "overridden"},
+			Annotations: map[string]string{"backup": "daily"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOncePod},
+			DataSource:       dataSource,
+			VolumeMode:       &block,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		},
+	}
+
+	pvcs := NewPersistentVolumeClaimForMinIOPool(context.TODO(), minio, pool)
+	wantNames := []string{
+		"data0-minio-ss-pool-0-0", "data1-minio-ss-pool-0-0",
+		"data0-minio-ss-pool-0-1", "data1-minio-ss-pool-0-1",
+	}
+	if len(pvcs) != len(wantNames) {
+		t.Fatalf("got %d PVCs, want %d", len(pvcs), len(wantNames))
+	}
+	wantLabels := map[string]string{"team": "storage", miniov1alpha1.MinIOLable: minio.Name, miniov1alpha1.PoolLabel: pool.Name}
+	for i, pvc := range pvcs {
+		if pvc.Name != wantNames[i] || pvc.Namespace != minio.Namespace {
+			t.Errorf("PVC %d = %s/%s, want %s/%s", i, pvc.Namespace, pvc.Name, minio.Namespace, wantNames[i])
+		}
+		if !reflect.DeepEqual(pvc.Labels, wantLabels) {
+			t.Errorf("PVC %s labels = %v, want %v", pvc.Name, pvc.Labels, wantLabels)
+		}
+		if pvc.Annotations["backup"] != "daily" {
+			t.Errorf("PVC %s annotations = %v, want backup=daily", pvc.Name, pvc.Annotations)
+		}
+		if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName != storageClass {
+			t.Errorf("PVC %s storageClassName = %v, want %s", pvc.Name, pvc.Spec.StorageClassName, storageClass)
+		}
+		if !reflect.DeepEqual(pvc.Spec.AccessModes, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOncePod}) {
+			t.Errorf("PVC %s accessModes = %v, want ReadWriteOncePod", pvc.Name, pvc.Spec.AccessModes)
+		}
+		if !reflect.DeepEqual(pvc.Spec.DataSource, dataSource) {
+			t.Errorf("PVC %s dataSource = %v, want %v", pvc.Name, pvc.Spec.DataSource, dataSource)
+		}
+		if pvc.Spec.VolumeMode == nil || *pvc.Spec.VolumeMode != block {
+			t.Errorf("PVC %s volumeMode = %v, want Block", pvc.Name, pvc.Spec.VolumeMode)
+		}
+		if len(pvc.OwnerReferences) != 0 {
+			t.Errorf("PVC %s ownerReferences = %v, want none without ReclaimStorage", pvc.Name, pvc.OwnerReferences)
+		}
+	}
+	// 渲染 PVC 时不能修改服务池中的模板
+	if pool.VolumeClaimTemplate.Labels[miniov1alpha1.PoolLabel] != "overridden" {
+		t.Errorf("pool template labels were modified, %v", pool.VolumeClaimTemplate.Labels)
+	}
+
+	minio.Spec.ReclaimStorage = true
+	for _, pvc := range NewPersistentVolumeClaimForMinIOPool(context.TODO(), minio, pool) {
+		if !metav1.IsControlledBy(pvc, minio) {
+			t.Errorf("PVC %s ownerReferences = %v, want controlled by MinIO with ReclaimStorage", pvc.Name, pvc.OwnerReferences)
+		}
+	}
+}
+
+func TestNewPersistentVolumeClaimDefaults(t *testing.T) {
+	minio := newTestMinIO()
+	pool := &minio.Spec.Pools[0]
+	pvc := NewPersistentVolumeClaimForMinIOPool(context.TODO(), minio, pool)[0]
+	if pvc.Name != miniov1alpha1.MinIOVolumeName+"0-minio-ss-pool-0-0" {
+		t.Errorf("PVC name = %s", pvc.Name)
+	}
+	if !reflect.DeepEqual(pvc.Spec.AccessModes, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}) {
+		t.Errorf("accessModes = %v, want ReadWriteOnce", pvc.Spec.AccessModes)
+	}
+	if !reflect.DeepEqual(pvc.Labels, minio.MinIOPoolLabels(pool)) {
+		t.Errorf("labels = %v, want %v", pvc.Labels, minio.MinIOPoolLabels(pool))
+	}
+}
+
+func TestNewVolumeClaimTemplatesForMinIOPool(t *testing.T) {
+	minio := newTestMinIO()
+	minio.UID = "minio-uid"
+	minio.Spec.ReclaimStorage = true
+	pool := &minio.Spec.Pools[0]
+	pool.VolumesPerServer = 3
+
+	templates := NewVolumeClaimTemplatesForMinIOPool(minio, pool)
+	if len(templates) != 3 {
+		t.Fatalf("got %d templates, want 3", len(templates))
+	}
+	for i, template := range templates {
+		if template.Name != PoolVolumeName(pool, i) {
+			t.Errorf("template %d name = %s, want %s", i, template.Name, PoolVolumeName(pool, i))
+		}
+		if len(template.OwnerReferences) != 0 {
+			t.Errorf("template %s ownerReferences = %v, want none", template.Name, template.OwnerReferences)
+		}
+	}
+}
+
+func TestSyncPersistentVolumeClaimOwner(t *testing.T) {
+	otherOwner := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: types.UID("other-uid")}
+	tests := []struct {
+		name           string
+		reclaimStorage bool
+		owned          bool
+		wantChanged    bool
+		wantOwned      bool
+	}{
+		{name: "reclaim adds the owner", reclaimStorage: true, wantChanged: true, wantOwned: true},
+		{name: "reclaim keeps the owner", reclaimStorage: true, owned: true, wantOwned: true},
+		{name: "retain removes the owner", owned: true, wantChanged: true},
+		{name: "retain without owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minio := newTestMinIO()
+			minio.UID = "minio-uid"
+			minio.Spec.ReclaimStorage = tt.reclaimStorage
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data0-minio-ss-pool-0-0", OwnerReferences: []metav1.OwnerReference{otherOwner}},
+			}
+			if tt.owned {
+				pvc.OwnerReferences = append(pvc.OwnerReferences, minio.OwnerRef()...)
+			}
+
+			if changed := SyncPersistentVolumeClaimOwner(minio, pvc); changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if owned := metav1.IsControlledBy(pvc, minio); owned != tt.wantOwned {
+				t.Errorf("owned = %v, want %v", owned, tt.wantOwned)
+			}
+			// 其他 OwnerReference 保持不变
+			found := false
+			for _, ref := range pvc.OwnerReferences {
+				if ref.UID == otherOwner.UID {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("ownerReferences = %v, want %s kept", pvc.OwnerReferences, otherOwner.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// 生成的 root 用户名和密码的长度
+	rootUserLength     = 20
+	rootPasswordLength = 40
+
+	credentialCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// config.env 中表示 root 用户名和密码的环境变量
+var rootCredentialKeys = map[string]bool{
+	"MINIO_ROOT_USER":     true,
+	"MINIO_ROOT_PASSWORD": true,
+	"MINIO_ACCESS_KEY":    true,
+	"MINIO_SECRET_KEY":    true,
+}
+
+// 根据 MinIO 实例创建配置 Secret，随机生成 root 用户名和密码
+func NewConfigurationSecretForMinIO(minio *miniov1alpha1.MinIO) (*corev1.Secret, error) {
+	accessKey, err := randomString(rootUserLength)
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := randomString(rootPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            minio.ConfigurationSecretName(),
+			Namespace:       minio.Namespace,
+			Labels:          minio.MinIOPodLabels(),
+			OwnerReferences: minio.OwnerRef(),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"config.env": NewRawConfiguration(accessKey, secretKey),
+		},
+	}, nil
+}
+
+// 生成 config.env 的内容
+func NewRawConfiguration(accessKey, secretKey string) []byte {
+	return []byte(fmt.Sprintf("export MINIO_ROOT_USER=\"%s\"\nexport MINIO_ROOT_PASSWORD=\"%s\"\n", accessKey, secretKey))
+}
+
+// 随机生成新的 root 用户名和密码并替换 config.env 中原有的值，其他配置保持不变
+func RotateRawConfiguration(configuration []byte) ([]byte, error) {
+	accessKey, err := randomString(rootUserLength)
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := randomString(rootPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(configuration))
+	for scanner.Scan() {
+		line := scanner.Text()
+		ekv, err := parsEnvEntry(line)
+		if err == nil && !ekv.Skip && rootCredentialKeys[ekv.Key] {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	raw := []byte(strings.Join(lines, "\n"))
+	if len(raw) > 0 {
+		raw = append(raw, '\n')
+	}
+	return append(raw, NewRawConfiguration(accessKey, secretKey)...), nil
+}
+
+// 使用 crypto/rand 生成由字母和数字组成的随机字符串
+func randomString(length int) (string, error) {
+	max := big.NewInt(int64(len(credentialCharset)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = credentialCharset[n.Int64()]
+	}
+	return string(b), nil
+}
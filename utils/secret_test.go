@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRotateRawConfiguration(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuration string
+		keep          []string
+	}{
+		{
+			name:          "generated configuration",
+			configuration: string(NewRawConfiguration("olduser", "oldpassword")),
+		},
+		{
+			name:          "legacy keys and other settings",
+			configuration: "export MINIO_ACCESS_KEY=\"olduser\"\nexport MINIO_SECRET_KEY=\"oldpassword\"\n\nexport MINIO_BROWSER=\"off\"\n",
+			keep:          []string{"MINIO_BROWSER"},
+		},
+		{
+			name:          "without trailing newline",
+			configuration: "export MINIO_STORAGE_CLASS_STANDARD=\"EC:2\"\nexport MINIO_ROOT_USER=\"olduser\"",
+			keep:          []string{"MINIO_STORAGE_CLASS_STANDARD"},
+		},
+		{
+			name: "empty configuration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotated, err := RotateRawConfiguration([]byte(tt.configuration))
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := ParseRawConfiguration(rotated)
+			accessKey, secretKey := string(config["accesskey"]), string(config["secretkey"])
+			if len(accessKey) != rootUserLength || len(secretKey) != rootPasswordLength {
+				t.Errorf("unexpected credential length %d/%d", len(accessKey), len(secretKey))
+			}
+			if accessKey == "olduser" || secretKey == "oldpassword" {
+				t.Errorf("credentials not rotated")
+			}
+			for _, key := range []string{"MINIO_ACCESS_KEY", "MINIO_SECRET_KEY"} {
+				if _, ok := config[key]; ok {
+					t.Errorf("legacy key %s not removed", key)
+				}
+			}
+			for _, key := range tt.keep {
+				if _, ok := config[key]; !ok {
+					t.Errorf("setting %s not kept", key)
+				}
+			}
+			if strings.Count(string(rotated), "MINIO_ROOT_USER") != 1 || strings.Count(string(rotated), "MINIO_ROOT_PASSWORD") != 1 {
+				t.Errorf("root credentials should appear once, got\n%s", rotated)
+			}
+		})
+	}
+
+	first, _ := RotateRawConfiguration(NewRawConfiguration("olduser", "oldpassword"))
+	second, _ := RotateRawConfiguration(NewRawConfiguration("olduser", "oldpassword"))
+	if string(first) == string(second) {
+		t.Errorf("rotated credentials should be random")
+	}
+}
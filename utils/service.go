@@ -63,14 +63,14 @@ func NewConsoleServiceForMinIO(m *miniov1alpha1.MinIO) *corev1.Service {
 		TargetPort: intstr.FromInt(miniov1alpha1.ConsolePort),
 	}
 
-	// TODO:暂不启用 tls
-	// if m.TLS() {
-	// 	consolePort = corev1.ServicePort{
-	// 		Name:       miniov1alpha1.ConsoleServiceTLSPortName,
-	// 		Port:       miniov1alpha1.ConsoleTLSPort,
-	// 		TargetPort: intstr.FromInt(miniov1alpha1.ConsoleTLSPort),
-	// 	}
-	// }
+	// 开启 TLS 时 Console 与 MinIO 服务使用相同的证书，监听 ConsoleTLSPort
+	if m.TLS() {
+		consolePort = corev1.ServicePort{
+			Name:       miniov1alpha1.ConsoleServiceTLSPortName,
+			Port:       miniov1alpha1.ConsoleTLSPort,
+			TargetPort: intstr.FromInt(miniov1alpha1.ConsoleTLSPort),
+		}
+	}
 
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -125,6 +125,22 @@ func NewHeadlessServiceForMinIO(m *miniov1alpha1.MinIO) *corev1.Service {
 	return svc
 }
 
+// 使用期望的 Service 规格更新 Service，保留由集群分配的 ClusterIP
+func SyncServiceSpec(svc *corev1.Service, expectedSvc *corev1.Service) {
+	clusterIP, clusterIPs := svc.Spec.ClusterIP, svc.Spec.ClusterIPs
+	svc.Spec = expectedSvc.Spec
+	if expectedSvc.Spec.ClusterIP == "" {
+		svc.Spec.ClusterIP, svc.Spec.ClusterIPs = clusterIP, clusterIPs
+	}
+}
+
+// spec.clusterIP 不可修改，Service 在 Headless 与分配 ClusterIP 之间切换时需要删除后重新创建
+func ServiceNeedsRecreate(svc *corev1.Service, expectedSvc *corev1.Service) bool {
+	headless := svc.Spec.ClusterIP == corev1.ClusterIPNone
+	expectedHeadless := expectedSvc.Spec.ClusterIP == corev1.ClusterIPNone
+	return headless != expectedHeadless
+}
+
 // 校验 Service 是否有更新
 func MinioSvcMatchesSpecification(svc *corev1.Service, expectedSvc *corev1.Service) (bool, error) {
 	for k, expVal := range expectedSvc.ObjectMeta.Labels {
@@ -143,12 +159,23 @@ func MinioSvcMatchesSpecification(svc *corev1.Service, expectedSvc *corev1.Servi
 	}
 
 	for i, expPort := range expectedSvc.Spec.Ports {
+		// 未设置 targetPort 时由集群设置为 port
+		targetPort := expPort.TargetPort
+		if targetPort == (intstr.IntOrString{}) {
+			targetPort = intstr.FromInt(int(expPort.Port))
+		}
 		if expPort.Name != svc.Spec.Ports[i].Name ||
 			expPort.Port != svc.Spec.Ports[i].Port ||
-			expPort.TargetPort != svc.Spec.Ports[i].TargetPort {
+			targetPort != svc.Spec.Ports[i].TargetPort {
 			return false, errors.New("service ports don't match")
 		}
 	}
+	if expectedSvc.Spec.ClusterIP == corev1.ClusterIPNone && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		return false, errors.New("service is not headless")
+	}
+	if svc.Spec.PublishNotReadyAddresses != expectedSvc.Spec.PublishNotReadyAddresses {
+		return false, errors.New("publishNotReadyAddresses doesn't match")
+	}
 	// compare selector
 	if !equality.Semantic.DeepDerivative(expectedSvc.Spec.Selector, svc.Spec.Selector) {
 		// some field set by the operator has changed
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"testing"
+
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newTestMinIO() *miniov1alpha1.MinIO {
+	return &miniov1alpha1.MinIO{
+		ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "default"},
+		Spec: miniov1alpha1.MinIOSpec{
+			Image: "minio/minio",
+			Pools: []miniov1alpha1.Pool{
+				{Name: "pool-0", Servers: 4, VolumesPerServer: 1},
+			},
+		},
+	}
+}
+
+// 模拟集群创建 Service 后设置的默认值
+func createdService(svc *corev1.Service, clusterIP string) *corev1.Service {
+	created := svc.DeepCopy()
+	if created.Spec.ClusterIP == "" {
+		created.Spec.ClusterIP = clusterIP
+	}
+	created.Spec.ClusterIPs = []string{created.Spec.ClusterIP}
+	for i := range created.Spec.Ports {
+		if created.Spec.Ports[i].TargetPort == (intstr.IntOrString{}) {
+			created.Spec.Ports[i].TargetPort = intstr.FromInt(int(created.Spec.Ports[i].Port))
+		}
+		created.Spec.Ports[i].Protocol = corev1.ProtocolTCP
+	}
+	return created
+}
+
+func TestHeadlessServiceMatchesSpecification(t *testing.T) {
+	minio := newTestMinIO()
+	expected := NewHeadlessServiceForMinIO(minio)
+	svc := createdService(expected, "")
+
+	if ok, err := MinioSvcMatchesSpecification(svc, expected); !ok {
+		t.Fatalf("created headless Service should match, %v", err)
+	}
+	if ok, _ := MinioSvcMatchesSpecification(svc, NewConsoleServiceForMinIO(minio)); ok {
+		t.Fatalf("headless Service should not match the console Service")
+	}
+
+	svc.Spec.PublishNotReadyAddresses = false
+	if ok, _ := MinioSvcMatchesSpecification(svc, expected); ok {
+		t.Fatalf("headless Service without publishNotReadyAddresses should not match")
+	}
+
+	SyncServiceSpec(svc, expected)
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Errorf("clusterIP = %q, want None", svc.Spec.ClusterIP)
+	}
+	if !svc.Spec.PublishNotReadyAddresses {
+		t.Errorf("publishNotReadyAddresses = false, want true")
+	}
+	if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != miniov1alpha1.MinIOPort {
+		t.Errorf("ports = %v, want MinIO port %d", svc.Spec.Ports, miniov1alpha1.MinIOPort)
+	}
+}
+
+func TestSyncServiceSpec(t *testing.T) {
+	minio := newTestMinIO()
+	svc := createdService(NewConsoleServiceForMinIO(minio), "10.0.0.10")
+
+	// 开启 TLS 后 Console 使用 ConsoleTLSPort
+	minio.Spec.EnableCert = true
+	expected := NewConsoleServiceForMinIO(minio)
+	if ok, _ := MinioSvcMatchesSpecification(svc, expected); ok {
+		t.Fatalf("console Service should not match after enabling TLS")
+	}
+
+	SyncServiceSpec(svc, expected)
+	if svc.Spec.ClusterIP != "10.0.0.10" || len(svc.Spec.ClusterIPs) != 1 || svc.Spec.ClusterIPs[0] != "10.0.0.10" {
+		t.Errorf("clusterIP = %q %v, want allocated 10.0.0.10 kept", svc.Spec.ClusterIP, svc.Spec.ClusterIPs)
+	}
+	if svc.Spec.Ports[0].Name != miniov1alpha1.ConsoleServiceTLSPortName || svc.Spec.Ports[0].Port != miniov1alpha1.ConsoleTLSPort {
+		t.Errorf("port = %v, want %s:%d", svc.Spec.Ports[0], miniov1alpha1.ConsoleServiceTLSPortName, miniov1alpha1.ConsoleTLSPort)
+	}
+	if ok, err := MinioSvcMatchesSpecification(svc, expected); !ok {
+		t.Errorf("synced Service should match, %v", err)
+	}
+}
+
+func TestServiceNeedsRecreate(t *testing.T) {
+	minio := newTestMinIO()
+	headless := NewHeadlessServiceForMinIO(minio)
+	console := NewConsoleServiceForMinIO(minio)
+	tests := []struct {
+		name     string
+		svc      *corev1.Service
+		expected *corev1.Service
+		want     bool
+	}{
+		{"headless Service", createdService(headless, ""), headless, false},
+		{"headless Service with allocated clusterIP", createdService(console, "10.0.0.10"), headless, true},
+		{"clusterIP Service", createdService(console, "10.0.0.10"), console, false},
+		{"clusterIP Service without clusterIP", createdService(headless, ""), console, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ServiceNeedsRecreate(tt.svc, tt.expected); got != tt.want {
+				t.Errorf("ServiceNeedsRecreate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	miniov1alpha1 "minio-operator/api/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// 根据 MinIO 服务池构建 StatefulSet 实例，annotations 会添加到 Pod 模板中
+func NewStatefulSetForMinIOPool(minio *miniov1alpha1.MinIO, pool *miniov1alpha1.Pool, annotations map[string]string) *appsv1.StatefulSet {
+	replicas := int32(pool.Servers)
+	labels := minio.MinIOPoolLabels(pool)
+	maxUnavailable := intstr.FromInt(minio.PoolMaxUnavailable(pool))
+
+	ss := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            minio.PoolStatefulSetName(pool),
+			Namespace:       minio.Namespace,
+			Labels:          labels,
+			OwnerReferences: minio.OwnerRef(),
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: minio.MinIOHLServiceName(),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			PodManagementPolicy: miniov1alpha1.DefaultPodManagementPolicy,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: miniov1alpha1.DefaultUpdateStrategy,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+					MaxUnavailable: &maxUnavailable,
+				},
+			},
+			Template:             NewPodTemplateForMinIOPool(minio, pool, annotations),
+			VolumeClaimTemplates: NewVolumeClaimTemplatesForMinIOPool(minio, pool),
+		},
+	}
+
+	return ss
+}
+
+// 返回 StatefulSet 滚动更新的 partition，序号大于等于 partition 的 Pod 才会被更新
+func StatefulSetPartition(ss *appsv1.StatefulSet) int32 {
+	if ss.Spec.UpdateStrategy.RollingUpdate == nil || ss.Spec.UpdateStrategy.RollingUpdate.Partition == nil {
+		return 0
+	}
+	return *ss.Spec.UpdateStrategy.RollingUpdate.Partition
+}